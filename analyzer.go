@@ -1,31 +1,172 @@
 package mingo
 
-import "golang.org/x/tools/go/analysis"
+import (
+	"fmt"
+	"reflect"
+	"sync"
 
-// Analyzer produces an [analysis.Analyzer] that can be used to scan packages.
-// The result (which may depend on scanning multiple packages)
-// is available in s.Result.
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer is an [analysis.Analyzer] for mingo,
+// suitable for use with go vet -vettool (see cmd/mingovet),
+// [golang.org/x/tools/go/analysis/multichecker],
+// and golangci-lint (see the golangci subpackage).
+//
+// For each package,
+// it exports a [*PackageFact] giving the minimum version of Go needed to build the package
+// (including the packages it imports,
+// but not the standard library).
+// The same value is the analyzer's result
+// (nil for packages that are not scanned, see below).
+//
+// It reports a diagnostic for each use of a language feature or stdlib identifier
+// that requires a Go version above a target,
+// and for each use of a feature that stops working in some version of Go
+// (see [ScanResult.Warnings]).
+// The target is given by the analyzer's -target flag
+// (a minor version of Go 1.x),
+// defaulting to the version declared in the package's go.mod.
+//
+// Packages with no module information,
+// such as those in the standard library,
+// are not scanned.
+var Analyzer = new(Scanner).newAnalyzer()
+
+// Analyzer produces an [analysis.Analyzer] like the package-level [Analyzer]
+// but using the settings in s
+// (HistDir and Verbose).
+// The analyzer does not change s,
+// and can run on multiple packages concurrently.
 func (s *Scanner) Analyzer() (*analysis.Analyzer, error) {
 	if err := s.ensureHistory(); err != nil {
 		return nil, err
 	}
+	return s.newAnalyzer(), nil
+}
+
+func (s *Scanner) newAnalyzer() *analysis.Analyzer {
+	r := &analyzerRun{s: s}
+
+	a := &analysis.Analyzer{
+		Name:       "mingo",
+		Doc:        "mingo finds the minimum version of Go that can build a module\n\nIt reports uses of language features and stdlib identifiers newer than the -target version of Go.",
+		URL:        "https://github.com/bobg/mingo",
+		Run:        r.run,
+		FactTypes:  []analysis.Fact{new(PackageFact)},
+		ResultType: reflect.TypeFor[*PackageFact](),
+	}
+	a.Flags.IntVar(&r.target, "target", 0, "minor version of Go 1.x to report findings above (default: the version in go.mod)")
+
+	return a
+}
+
+// PackageFact is the [analysis.Fact] that [Analyzer] exports for each package.
+// It also implements [Result].
+type PackageFact struct {
+	Minor int    // The lowest minor version of Go 1.x that can build the package and its imports.
+	Desc  string // A description of the reason for Minor.
+}
+
+func (*PackageFact) AFact() {}
+
+func (f *PackageFact) Version() int { return f.Minor }
+
+func (f *PackageFact) String() string {
+	if f.Desc == "" {
+		return fmt.Sprintf("1.%d", f.Minor)
+	}
+	return fmt.Sprintf("1.%d (%s)", f.Minor, f.Desc)
+}
+
+type analyzerRun struct {
+	s      *Scanner
+	target int
+
+	once sync.Once
+	err  error
+}
+
+func (r *analyzerRun) run(pass *analysis.Pass) (any, error) {
+	r.once.Do(func() { r.err = r.s.ensureHistory() })
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if pass.Module == nil || pass.Module.Path == "" {
+		// E.g. the stdlib.
+		return (*PackageFact)(nil), nil
+	}
+
+	target := r.target
+	if target == 0 && pass.Module.GoVersion != "" {
+		var err error
+		if target, err = parseGoVersion(pass.Module.GoVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	p := r.s.newPkgScanner(pass.Pkg, pass.Fset, pass.TypesInfo)
+	p.report = target > 0
+	p.target = target
+
+	if err := p.files(pass.Files); err != nil {
+		return nil, err
+	}
 
-	s.Result = intResult(0)
+	fact := &PackageFact{Minor: p.res.Version()}
+	if p.res.Version() > 0 {
+		fact.Desc = p.res.String()
+	}
+	for _, imp := range pass.Pkg.Imports() {
+		var impFact PackageFact
+		if pass.ImportPackageFact(imp, &impFact) && impFact.Minor > fact.Minor {
+			fact.Minor = impFact.Minor
+			fact.Desc = fmt.Sprintf("import %q: %s", imp.Path(), impFact.Desc)
+		}
+	}
+	pass.ExportPackageFact(fact)
 
-	return &analysis.Analyzer{
-		Name: "mingo",
-		Doc:  "mingo finds the minimum version of Go that can build a module",
-		Run:  s.runAnalyzer,
-	}, nil
+	for _, f := range p.findings {
+		pass.Report(p.diagnostic(f))
+	}
+	for _, w := range p.warnings {
+		pass.Report(analysis.Diagnostic{
+			Pos:      w.at,
+			Category: w.rule,
+			Message:  "warning: " + w.desc,
+		})
+	}
+
+	return fact, nil
 }
 
-func (s *Scanner) runAnalyzer(pass *analysis.Pass) (any, error) {
-	var (
-		pkgpath = pass.Pkg.Path()
-		fset    = pass.Fset
-		info    = pass.TypesInfo
-		files   = pass.Files
-	)
-	err := s.scanPackageHelper(pkgpath, fset, info, files)
-	return nil, err
+func (p *pkgScanner) diagnostic(f posResult) analysis.Diagnostic {
+	d := analysis.Diagnostic{
+		Pos:      f.at,
+		Category: "stdlib",
+		Message:  fmt.Sprintf("%s requires Go 1.%d, above target 1.%d", f.desc, f.version, p.target),
+	}
+	if f.semantic {
+		d.Message = fmt.Sprintf("warning: %s behaves differently before Go 1.%d, and target 1.%d is below that (no compile error)", f.desc, f.version, p.target)
+	}
+	if f.provisional {
+		d.Message += " [provisional]"
+	}
+	if f.rule != "" {
+		d.Category = f.rule
+		for _, rule := range p.langRules {
+			if rule.ID == f.rule {
+				d.URL = rule.Link
+				break
+			}
+		}
+	}
+	if len(f.fix) > 0 {
+		d.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   fmt.Sprintf("Rewrite without %s", f.desc),
+			TextEdits: f.fix,
+		}}
+	}
+	return d
 }
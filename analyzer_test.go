@@ -0,0 +1,82 @@
+package mingo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, "testdata/analyzer", Analyzer, "./...")
+}
+
+func TestAnalyzerTarget(t *testing.T) {
+	var s Scanner
+	a, err := s.Analyzer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Flags.Set("target", "24"); err != nil {
+		t.Fatal(err)
+	}
+
+	// With a target of 1.24, there are no diagnostics,
+	// and the package facts are as in testdata/analyzer.
+	results := analysistest.Run(t, "testdata/analyzertarget", a, "./...")
+	for _, res := range results {
+		fact, ok := res.Result.(*PackageFact)
+		if !ok || fact.Version() != 24 {
+			t.Errorf("got result %v for %s, want version 24", res.Result, res.Action.Package.PkgPath)
+		}
+	}
+}
+
+func TestAnalyzerFixes(t *testing.T) {
+	var s Scanner
+	a, err := s.Analyzer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Flags.Set("target", "12"); err != nil {
+		t.Fatal(err)
+	}
+	analysistest.RunWithSuggestedFixes(t, "testdata/fixes", a, "./...")
+
+	// The fixed code no longer has the findings that were fixed.
+	// (The golden files keep the "want" comments of the original lines.)
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/fixes")); err != nil {
+		t.Fatal(err)
+	}
+	goldens, err := filepath.Glob(filepath.Join(dir, "*", "*.go.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, golden := range goldens {
+		if err := os.Rename(golden, strings.TrimSuffix(golden, ".golden")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: Mode, Dir: dir}, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		p := s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+		p.report = true
+		p.target = 12
+		if err := p.files(pkg.Syntax); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range p.findings {
+			switch f.rule {
+			case "expanded-numeric-literal", "any":
+				t.Errorf("%s: got %s (%s) after fixing", f.pos, f.desc, f.rule)
+			}
+		}
+	}
+}
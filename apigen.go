@@ -0,0 +1,488 @@
+package mingo
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/packages"
+)
+
+// APIGen generates API history files for a module from its released versions,
+// suitable for use with [Scanner.ModHist].
+type APIGen struct {
+	// GitDir, if set, is the module's directory in a git checkout.
+	// The semver tags for the module are the versions to scan:
+	// those with the module's major version
+	// and, for a module in a subdirectory sub of the repository,
+	// the sub/ prefix (as in sub/v1.2.3).
+	// Otherwise the versions are the ones present in the module cache.
+	GitDir string
+
+	Verbose bool // be verbose
+}
+
+// Run writes one file for each released version of the module modpath into outdir,
+// named for the version (v1.2.0.txt etc.).
+// Each file lists the exported API added in that version
+// (compared with all earlier versions)
+// in the format of the go1.*.txt files in $GOROOT/api.
+func (g APIGen) Run(modpath, outdir string) error {
+	var src apiGenSource
+	if g.GitDir != "" {
+		gs, err := newGitSource(g.GitDir, modpath)
+		if err != nil {
+			return err
+		}
+		src = gs
+	} else {
+		src = modCacheSource{modpath: modpath}
+	}
+
+	versions, err := src.versions()
+	if err != nil {
+		return errors.Wrapf(err, "listing versions of %s", modpath)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no released versions of %s found", modpath)
+	}
+
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		return errors.Wrapf(err, "creating %s", outdir)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, version := range versions {
+		g.verbosef("scanning %s@%s", modpath, version)
+
+		lines, err := g.versionAPI(src, version)
+		if err != nil {
+			return errors.Wrapf(err, "computing API of %s@%s", modpath, version)
+		}
+
+		var added []string
+		for _, line := range lines {
+			if !seen[line] {
+				added = append(added, line)
+				seen[line] = true
+			}
+		}
+
+		var buf bytes.Buffer
+		for _, line := range added {
+			fmt.Fprintln(&buf, line)
+		}
+		filename := filepath.Join(outdir, version+".txt")
+		if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", filename)
+		}
+	}
+
+	return nil
+}
+
+func (g APIGen) versionAPI(src apiGenSource, version string) ([]string, error) {
+	dir, cleanup, err := src.dir(version)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return ModuleAPI(dir)
+}
+
+func (g APIGen) verbosef(format string, args ...any) {
+	if !g.Verbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+type apiGenSource interface {
+	// Method versions returns the available released versions, in increasing order.
+	versions() ([]string, error)
+
+	// Method dir returns a directory containing the module at the given version,
+	// plus a function for cleaning it up afterwards.
+	dir(version string) (string, func(), error)
+}
+
+type modCacheSource struct {
+	modpath string
+}
+
+func (s modCacheSource) modCacheDir() (string, error) {
+	out, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil {
+		return "", errors.Wrap(err, "getting GOMODCACHE")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (s modCacheSource) versions() ([]string, error) {
+	cacheDir, err := s.modCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	escaped, err := module.EscapePath(s.modpath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "escaping module path %s", s.modpath)
+	}
+
+	var (
+		parent = filepath.Join(cacheDir, filepath.FromSlash(path.Dir(escaped)))
+		prefix = path.Base(escaped) + "@"
+	)
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", parent)
+	}
+
+	var result []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		version, err := module.UnescapeVersion(strings.TrimPrefix(name, prefix))
+		if err != nil {
+			continue
+		}
+		if isReleased(version) {
+			result = append(result, version)
+		}
+	}
+
+	sortVersions(result)
+	return result, nil
+}
+
+func (s modCacheSource) dir(version string) (string, func(), error) {
+	cacheDir, err := s.modCacheDir()
+	if err != nil {
+		return "", nil, err
+	}
+	mv := module.Version{Path: s.modpath, Version: version}
+	escaped, err := module.EscapePath(mv.Path)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "escaping module path %s", mv.Path)
+	}
+	escapedVersion, err := module.EscapeVersion(mv.Version)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "escaping version %s", mv.Version)
+	}
+	return filepath.Join(cacheDir, filepath.FromSlash(escaped)+"@"+escapedVersion), func() {}, nil
+}
+
+type gitSource struct {
+	repo      string // the module's directory in a git checkout
+	root      string // the root of the checkout
+	subdir    string // the module's directory relative to root, or "" for the root
+	tagPrefix string // the prefix of the module's tags, like "sub/", or ""
+	pathMajor string // the major-version suffix of the module path, like "/v2", or ""
+}
+
+// Function newGitSource returns a gitSource for the module modpath in dir.
+// As in the go command,
+// the tags of a module in a subdirectory of the repository have the subdirectory as a prefix,
+// less any final element naming the major version
+// (so the tags of example.com/repo/sub/v2 in sub/v2 look like sub/v2.0.0).
+func newGitSource(dir, modpath string) (gitSource, error) {
+	_, pathMajor, ok := module.SplitPathVersion(modpath)
+	if !ok {
+		return gitSource{}, fmt.Errorf("invalid module path %s", modpath)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return gitSource{}, errors.Wrapf(err, "finding %s in its git repository", dir)
+	}
+	lines := strings.Split(string(out), "\n")
+	if len(lines) < 2 {
+		return gitSource{}, fmt.Errorf("finding %s in its git repository: unexpected output %q", dir, out)
+	}
+	root, subdir := lines[0], strings.TrimSuffix(lines[1], "/")
+
+	tagDir := subdir
+	if strings.HasPrefix(pathMajor, "/") && path.Base(tagDir) == pathMajor[1:] {
+		tagDir = path.Dir(tagDir)
+	}
+	var tagPrefix string
+	if tagDir != "" && tagDir != "." {
+		tagPrefix = tagDir + "/"
+	}
+
+	return gitSource{repo: dir, root: root, subdir: subdir, tagPrefix: tagPrefix, pathMajor: pathMajor}, nil
+}
+
+func (s gitSource) versions() ([]string, error) {
+	out, err := exec.Command("git", "-C", s.repo, "tag", "--list", s.tagPrefix+"v*").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing tags in %s", s.repo)
+	}
+
+	var result []string
+	for _, tag := range strings.Fields(string(out)) {
+		version := strings.TrimPrefix(tag, s.tagPrefix)
+		if isReleased(version) && module.CheckPathMajor(version, s.pathMajor) == nil {
+			result = append(result, version)
+		}
+	}
+
+	sortVersions(result)
+	return result, nil
+}
+
+// Method dir extracts the module's tree at the tag for the given version into a temporary directory.
+func (s gitSource) dir(version string) (string, func(), error) {
+	tmpdir, err := os.MkdirTemp("", "mingo-apigen")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "creating temp dir")
+	}
+	cleanup := func() { os.RemoveAll(tmpdir) }
+
+	tag := s.tagPrefix + version
+	treeish := tag
+	if s.subdir != "" {
+		treeish += ":" + s.subdir
+	}
+	archive, err := exec.Command("git", "-C", s.root, "archive", "--format=tar", treeish).Output()
+	if err != nil {
+		cleanup()
+		return "", nil, errors.Wrapf(err, "archiving %s", tag)
+	}
+	if err := untar(bytes.NewReader(archive), tmpdir); err != nil {
+		cleanup()
+		return "", nil, errors.Wrapf(err, "extracting %s", version)
+	}
+
+	return tmpdir, cleanup, nil
+}
+
+func untar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("archive entry %s is outside the archive root", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Function isReleased tells whether version is a semver version
+// that is neither a prerelease nor a pseudo-version.
+func isReleased(version string) bool {
+	return semver.IsValid(version) && semver.Prerelease(version) == "" && !module.IsPseudoVersion(version)
+}
+
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool {
+		return semver.Compare(versions[i], versions[j]) < 0
+	})
+}
+
+// ModuleAPI type-checks the module rooted at dir
+// and returns its exported API,
+// in the format of the go1.*.txt files in $GOROOT/api,
+// sorted.
+// Internal packages, main packages, and tests are excluded.
+// If any package fails to load or type-check,
+// ModuleAPI returns an error listing them all.
+func ModuleAPI(dir string) ([]string, error) {
+	conf := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes,
+		Dir:  dir,
+		Env:  append(os.Environ(), "GOWORK=off"),
+	}
+	pkgs, err := packages.Load(conf, "./...")
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading packages in %s", dir)
+	}
+
+	var errs error
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			errs = errors.Join(errs, LoadError{Err: e, Path: pkg.PkgPath})
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	var lines []string
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.Name == "main" || isInternal(pkg.PkgPath) {
+			continue
+		}
+		lines = append(lines, packageAPI(pkg.Types)...)
+	}
+
+	sort.Strings(lines)
+	return slices.Compact(lines), nil
+}
+
+func isInternal(pkgpath string) bool {
+	for _, elem := range strings.Split(pkgpath, "/") {
+		if elem == "internal" || elem == "testdata" {
+			return true
+		}
+	}
+	return false
+}
+
+func packageAPI(pkg *types.Package) []string {
+	var (
+		lines     []string
+		qualifier = types.RelativeTo(pkg)
+		prefix    = "pkg " + pkg.Path() + ", "
+	)
+
+	add := func(format string, args ...any) {
+		lines = append(lines, prefix+fmt.Sprintf(format, args...))
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+
+		switch obj := obj.(type) {
+		case *types.Const:
+			add("const %s %s", name, types.TypeString(obj.Type(), qualifier))
+
+		case *types.Var:
+			add("var %s %s", name, types.TypeString(obj.Type(), qualifier))
+
+		case *types.Func:
+			add("func %s%s", name, signatureString(obj.Type().(*types.Signature), qualifier))
+
+		case *types.TypeName:
+			lines = append(lines, typeAPI(prefix, obj, qualifier)...)
+		}
+	}
+
+	return lines
+}
+
+func typeAPI(prefix string, obj *types.TypeName, qualifier types.Qualifier) []string {
+	var (
+		lines []string
+		name  = obj.Name()
+	)
+
+	add := func(format string, args ...any) {
+		lines = append(lines, prefix+fmt.Sprintf(format, args...))
+	}
+
+	switch under := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		add("type %s struct", name)
+		for f := range under.Fields() {
+			if f.Exported() {
+				add("type %s struct, %s %s", name, f.Name(), types.TypeString(f.Type(), qualifier))
+			}
+		}
+
+	case *types.Interface:
+		var methods []string
+		for m := range under.Methods() {
+			if m.Exported() {
+				methods = append(methods, m.Name())
+			}
+		}
+		if len(methods) > 0 {
+			add("type %s interface { %s }", name, strings.Join(methods, ", "))
+		} else {
+			add("type %s interface", name)
+		}
+		for m := range under.Methods() {
+			if m.Exported() {
+				add("type %s interface, %s%s", name, m.Name(), signatureString(m.Type().(*types.Signature), qualifier))
+			} else {
+				add("type %s interface, unexported methods", name)
+			}
+		}
+
+	default:
+		add("type %s %s", name, types.TypeString(under, qualifier))
+	}
+
+	if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+		return lines
+	}
+
+	var (
+		valueMethods = types.NewMethodSet(obj.Type())
+		ptrMethods   = types.NewMethodSet(types.NewPointer(obj.Type()))
+	)
+	for sel := range ptrMethods.Methods() {
+		m := sel.Obj()
+		if !m.Exported() {
+			continue
+		}
+		recv := name
+		if valueMethods.Lookup(m.Pkg(), m.Name()) == nil {
+			recv = "*" + name
+		}
+		add("method (%s) %s%s", recv, m.Name(), signatureString(m.Type().(*types.Signature), qualifier))
+	}
+
+	return lines
+}
+
+func signatureString(sig *types.Signature, qualifier types.Qualifier) string {
+	var buf bytes.Buffer
+	types.WriteSignature(&buf, sig, qualifier)
+	return buf.String()
+}
@@ -0,0 +1,202 @@
+package mingo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestModuleAPI(t *testing.T) {
+	got, err := ModuleAPI("testdata/modhist/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"pkg example.com/lib, func Bar() int",
+		"pkg example.com/lib, func Baz() int",
+		"pkg example.com/lib, func Foo() int",
+		"pkg example.com/lib, method (T) M()",
+		"pkg example.com/lib, method (T) N()",
+		"pkg example.com/lib, type T struct",
+		"pkg example.com/lib, type T struct, X int",
+		"pkg example.com/lib, type T struct, Y int",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestAPIGenGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	repo := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	write := func(name, contents string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("go.mod", "module example.com/lib\n\ngo 1.18\n")
+	write("lib.go", "package lib\n\nfunc Foo() int { return 1 }\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1.0.0")
+	git("tag", "v1.0.0")
+	write("lib.go", "package lib\n\nfunc Foo() int { return 1 }\n\nfunc Bar() int { return 2 }\n")
+	git("commit", "-q", "-a", "-m", "v1.1.0")
+	git("tag", "v1.1.0")
+	git("tag", "v1.2.0-rc.1")
+
+	outdir := t.TempDir()
+	g := APIGen{GitDir: repo}
+	if err := g.Run("example.com/lib", outdir); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readModHist("example.com/lib", outdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(m.versions, []string{"v1.0.0", "v1.1.0"}) {
+		t.Errorf("got versions %v, want [v1.0.0 v1.1.0]", m.versions)
+	}
+	if v := m.lookup("example.com/lib", "Foo", ""); v != "v1.0.0" {
+		t.Errorf("got %q for Foo, want v1.0.0", v)
+	}
+	if v := m.lookup("example.com/lib", "Bar", ""); v != "v1.1.0" {
+		t.Errorf("got %q for Bar, want v1.1.0", v)
+	}
+}
+
+func TestModuleAPIErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":       "module example.com/broken\n\ngo 1.18\n",
+		"ok/ok.go":     "package ok\n\nfunc Foo() int { return 1 }\n",
+		"bad/bad.go":   "package bad\n\nfunc Foo() int { return undefined }\n",
+		"worse/bad.go": "package worse\n\nfunc Foo() int { return \"x\" }\n",
+	}
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := ModuleAPI(dir)
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, pkgpath := range []string{"example.com/broken/bad", "example.com/broken/worse"} {
+		if !strings.Contains(err.Error(), pkgpath) {
+			t.Errorf("error does not mention %s: %s", pkgpath, err)
+		}
+	}
+}
+
+func TestAPIGenGitModules(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	repo := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	write := func(name, contents string) {
+		t.Helper()
+		filename := filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("go.mod", "module example.com/lib\n\ngo 1.18\n")
+	write("lib.go", "package lib\n\nfunc Foo() int { return 1 }\n")
+	write("sub/go.mod", "module example.com/lib/sub\n\ngo 1.18\n")
+	write("sub/sub.go", "package sub\n\nfunc Sub() int { return 1 }\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1.0.0")
+	git("tag", "v1.0.0")
+	git("tag", "sub/v1.0.0")
+	write("go.mod", "module example.com/lib/v2\n\ngo 1.18\n")
+	write("lib.go", "package lib\n\nfunc Foo() int { return 1 }\n\nfunc Bar() int { return 2 }\n")
+	write("sub/sub.go", "package sub\n\nfunc Sub() int { return 1 }\n\nfunc Sub2() int { return 2 }\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v2.0.0")
+	git("tag", "v2.0.0")
+	git("tag", "sub/v1.1.0")
+
+	cases := []struct {
+		dir, modpath string
+		want         []string
+	}{{
+		dir:     repo,
+		modpath: "example.com/lib",
+		want:    []string{"v1.0.0"},
+	}, {
+		dir:     repo,
+		modpath: "example.com/lib/v2",
+		want:    []string{"v2.0.0"},
+	}, {
+		dir:     filepath.Join(repo, "sub"),
+		modpath: "example.com/lib/sub",
+		want:    []string{"v1.0.0", "v1.1.0"},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.modpath, func(t *testing.T) {
+			outdir := t.TempDir()
+			g := APIGen{GitDir: tc.dir}
+			if err := g.Run(tc.modpath, outdir); err != nil {
+				t.Fatal(err)
+			}
+			m, err := readModHist(tc.modpath, outdir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(m.versions, tc.want) {
+				t.Errorf("got versions %v, want %v", m.versions, tc.want)
+			}
+		})
+	}
+
+	outdir := t.TempDir()
+	g := APIGen{GitDir: filepath.Join(repo, "sub")}
+	if err := g.Run("example.com/lib/sub", outdir); err != nil {
+		t.Fatal(err)
+	}
+	m, err := readModHist("example.com/lib/sub", outdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := m.lookup("example.com/lib/sub", "Sub2", ""); v != "v1.1.0" {
+		t.Errorf("got %q for Sub2, want v1.1.0", v)
+	}
+}
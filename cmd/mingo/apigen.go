@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func apigen(args []string) error {
+	var (
+		fs = flag.NewFlagSet("apigen", flag.ContinueOnError)
+
+		gitDir, outdir string
+		verbose        bool
+	)
+	fs.StringVar(&gitDir, "git", "", "take versions from the tags for MODULE in the git checkout containing its directory DIR, instead of the module cache")
+	fs.StringVar(&outdir, "o", "", "output directory (default: MODULE's last path element plus .api)")
+	fs.BoolVar(&verbose, "v", false, "be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: mingo apigen [-git DIR] [-o OUTDIR] [-v] MODULE")
+	}
+	modpath := fs.Arg(0)
+
+	if outdir == "" {
+		outdir = path.Base(modpath) + ".api"
+	}
+
+	g := mingo.APIGen{
+		GitDir:  gitDir,
+		Verbose: verbose,
+	}
+	return errors.Wrapf(g.Run(modpath, outdir), "generating API history for %s", modpath)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func compat(args []string) error {
+	var (
+		fs = flag.NewFlagSet("compat", flag.ContinueOnError)
+
+		api, pkgDir, target string
+		tests, verbose      bool
+	)
+	fs.StringVar(&target, "target", "", "version of Go to support, e.g. 1.20")
+	fs.StringVar(&pkgDir, "pkg", "internal/compat", "directory of the compat package, relative to the module root")
+	fs.StringVar(&api, "api", "", "path to api directory")
+	fs.BoolVar(&tests, "tests", false, "rewrite tests too")
+	fs.BoolVar(&verbose, "v", false, "be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if target == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: mingo compat -target 1.N [-pkg DIR] [-api API] [-tests] [-v] [DIR]")
+	}
+	minor, err := parseTarget(target)
+	if err != nil {
+		return err
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	g := mingo.CompatGen{
+		Target:  minor,
+		PkgDir:  pkgDir,
+		HistDir: api,
+		Tests:   tests,
+		Verbose: verbose,
+	}
+	result, unsupported, err := g.Run(dir)
+	if err != nil {
+		return errors.Wrapf(err, "generating compat package for %s", dir)
+	}
+
+	fmt.Println(result.Version())
+	if len(unsupported) > 0 {
+		fmt.Printf("No fallback for:\n")
+		for _, r := range unsupported {
+			fmt.Printf("  %s\n", r)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func downgrade(args []string) error {
+	var (
+		fs = flag.NewFlagSet("downgrade", flag.ContinueOnError)
+
+		api, deps, target string
+		tests, verbose    bool
+	)
+	fs.StringVar(&target, "target", "", "version of Go to rewrite for, e.g. 1.20")
+	fs.StringVar(&api, "api", "", "path to api directory")
+	fs.StringVar(&deps, "deps", "none", "which dependencies count toward lowering go.mod (all, direct, none)")
+	fs.BoolVar(&tests, "tests", false, "rewrite tests too")
+	fs.BoolVar(&verbose, "v", false, "be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if target == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: mingo downgrade -target 1.N [-api API] [-deps (all|direct|none)] [-tests] [-v] [DIR]")
+	}
+	switch deps {
+	case "all", "direct", "none":
+	default:
+		return fmt.Errorf("invalid value for -deps: %s (should be all, direct, or none)", deps)
+	}
+	minor, err := parseTarget(target)
+	if err != nil {
+		return err
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	d := mingo.Downgrader{
+		Target:   minor,
+		HistDir:  api,
+		Tests:    tests,
+		Verbose:  verbose,
+		Deps:     deps != "none",
+		Indirect: deps == "all",
+	}
+	result, unrewritten, err := d.Run(dir)
+	if err != nil {
+		return errors.Wrapf(err, "downgrading %s", dir)
+	}
+
+	fmt.Println(result.Version())
+	if len(unrewritten) > 0 {
+		fmt.Printf("Could not rewrite:\n")
+		for _, r := range unrewritten {
+			fmt.Printf("  %s\n", r)
+		}
+	}
+	if result.Version() > minor {
+		return fmt.Errorf("minimum version 1.%d is still above target 1.%d [%s]", result.Version(), minor, result)
+	}
+	return nil
+}
+
+// Function parseTarget parses a Go version like 1.20 (or just 20),
+// returning the minor version.
+func parseTarget(s string) (int, error) {
+	minor, err := strconv.Atoi(strings.TrimPrefix(s, "1."))
+	if err != nil {
+		return 0, fmt.Errorf("invalid target %s (should be like 1.20)", s)
+	}
+	return minor, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func gate(args []string) error {
+	var (
+		fs = flag.NewFlagSet("gate", flag.ContinueOnError)
+
+		api, target    string
+		tests, verbose bool
+	)
+	fs.StringVar(&target, "target", "", "version of Go to build with, e.g. 1.20")
+	fs.StringVar(&api, "api", "", "path to api directory")
+	fs.BoolVar(&tests, "tests", false, "include tests")
+	fs.BoolVar(&verbose, "v", false, "be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if target == "" || fs.NArg() > 1 {
+		return fmt.Errorf("usage: mingo gate -target 1.N [-api API] [-tests] [-v] [DIR]")
+	}
+	minor, err := parseTarget(target)
+	if err != nil {
+		return err
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+
+	g := mingo.GatePlanner{
+		Target:  minor,
+		HistDir: api,
+		Tests:   tests,
+		Verbose: verbose,
+	}
+	plan, err := g.Run(dir)
+	if err != nil {
+		return errors.Wrapf(err, "planning %s", dir)
+	}
+
+	for _, m := range plan.Moves {
+		fmt.Printf("%s: move %s to a go1.%d file\n", m.Pos, m.Name, m.Version)
+		fmt.Printf("  %s\n", m.Reason)
+		switch {
+		case m.Missing:
+			fmt.Printf("  no stub possible: %s will be missing before Go 1.%d\n", m.Name, m.Version)
+		case m.Stub != "":
+			fmt.Printf("  stub:\n")
+			for _, line := range strings.Split(m.Stub, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	fmt.Println(plan.Result.Version())
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bobg/mingo"
+)
+
+func lsp(args []string) error {
+	var (
+		fs = flag.NewFlagSet("lsp", flag.ContinueOnError)
+
+		api, target    string
+		tests, verbose bool
+	)
+	fs.StringVar(&target, "target", "", "version of Go to report findings above, e.g. 1.20 (default: the version in go.mod)")
+	fs.StringVar(&api, "api", "", "path to api directory")
+	fs.BoolVar(&tests, "tests", false, "include tests")
+	fs.BoolVar(&verbose, "v", false, "log to stderr")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: mingo lsp [-target 1.N] [-api API] [-tests] [-v]")
+	}
+
+	ls := mingo.LangServer{
+		HistDir: api,
+		Tests:   tests,
+		Verbose: verbose,
+	}
+	if target != "" {
+		minor, err := parseTarget(target)
+		if err != nil {
+			return err
+		}
+		ls.Target = minor
+	}
+	return ls.Serve(os.Stdin, os.Stdout)
+}
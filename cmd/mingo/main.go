@@ -2,33 +2,76 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/bobg/errors"
 
 	"github.com/bobg/mingo"
 )
 
+// Subcommands maps the names of mingo subcommands
+// (given as the first command-line argument)
+// to the functions implementing them.
+// Each function receives the remaining arguments.
+var subcommands = map[string]func(args []string) error{
+	"apigen":    apigen,
+	"compat":    compat,
+	"downgrade": downgrade,
+	"gate":      gate,
+	"lsp":       lsp,
+	"rules":     rules,
+	"serve":     serve,
+}
+
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if len(os.Args) > 1 && isSubcommand(os.Args[1]) {
+		err = subcommands[os.Args[1]](os.Args[2:])
+	} else {
+		err = run()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
 }
 
+// Function isSubcommand tells whether arg names a subcommand.
+// An existing directory of the same name is the DIR to scan instead,
+// as it was before there were subcommands.
+func isSubcommand(arg string) bool {
+	if subcommands[arg] == nil {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err != nil || !info.IsDir()
+}
+
 func run() error {
 	var (
 		api, deps                     string
 		check, strict, tests, verbose bool
+		depversions, watch, verify    bool
+		gomod                         bool
+		modapi                        = make(modAPIFlag)
 	)
 	flag.StringVar(&api, "api", "", "path to api directory")
+	flag.Var(modapi, "modapi", "MOD=DIR: read API history for module MOD from directory DIR (may be repeated)")
 	flag.StringVar(&deps, "deps", "all", "which dependencies to scan (all, direct, none)")
+	flag.BoolVar(&depversions, "depversions", false, "compute the minimum version of each required module from the API used")
 	flag.BoolVar(&check, "check", false, "check that go.mod declares the right version of Go or higher")
 	flag.BoolVar(&strict, "strict", false, "check that go.mod declares exactly the right version of Go")
+	flag.BoolVar(&gomod, "gomod", false, "include the directives in go.mod and go.work that need newer versions of Go")
 	flag.BoolVar(&tests, "tests", false, "include tests")
 	flag.BoolVar(&verbose, "v", false, "be verbose")
+	flag.BoolVar(&verify, "verify", false, "cross-check the language version of each package with the type checker")
+	flag.BoolVar(&watch, "watch", false, "rescan as files change, printing the result and the findings that come and go")
 	flag.Parse()
 
 	dir := "."
@@ -48,23 +91,60 @@ func run() error {
 	}
 
 	s := mingo.Scanner{
-		HistDir:  api,
-		Verbose:  verbose,
-		Deps:     deps != "none",
-		Indirect: deps == "all",
-		Tests:    tests,
-		Check:    check,
-		Strict:   strict,
+		HistDir:     api,
+		Verbose:     verbose,
+		Deps:        deps != "none",
+		Indirect:    deps == "all",
+		Tests:       tests,
+		Check:       check,
+		Strict:      strict,
+		ModHist:     modapi,
+		DepVersions: depversions,
+		Verify:      verify,
+		GoMod:       gomod,
+	}
+
+	if watch {
+		return watchDir(&s, dir)
 	}
 
-	result, err := s.ScanDir(dir)
+	result, err := s.ScanDirContext(context.Background(), dir)
 	if err != nil {
 		return errors.Wrap(err, "scanning directory")
 	}
 
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
 	if !check {
 		fmt.Println(result.Version())
 	}
 
+	modpaths := slices.Sorted(maps.Keys(result.ModResults))
+	for _, modpath := range modpaths {
+		fmt.Printf("%s %s\n", modpath, result.ModResults[modpath].Version)
+	}
+
+	return nil
+}
+
+type modAPIFlag map[string]string
+
+func (f modAPIFlag) String() string {
+	var pairs []string
+	for modpath, dir := range f {
+		pairs = append(pairs, modpath+"="+dir)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f modAPIFlag) Set(val string) error {
+	modpath, dir, ok := strings.Cut(val, "=")
+	if !ok || modpath == "" || dir == "" {
+		return fmt.Errorf("invalid value %q (should be MOD=DIR)", val)
+	}
+	f[modpath] = dir
 	return nil
 }
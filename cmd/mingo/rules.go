@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func rules(args []string) error {
+	var (
+		fs = flag.NewFlagSet("rules", flag.ContinueOnError)
+
+		out string
+	)
+	fs.StringVar(&out, "o", "", "write to this file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: mingo rules [-o FILE]")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := mingo.RulesDoc(buf); err != nil {
+		return errors.Wrap(err, "producing rules doc")
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return errors.Wrapf(os.WriteFile(out, buf.Bytes(), 0644), "writing %s", out)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func serve(args []string) error {
+	var (
+		fs = flag.NewFlagSet("serve", flag.ContinueOnError)
+
+		addr, api, root string
+		verbose         bool
+	)
+	fs.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+	fs.StringVar(&root, "root", "", "directory under which module directories may be scanned (default: none)")
+	fs.StringVar(&api, "api", "", "path to api directory")
+	fs.BoolVar(&verbose, "v", false, "be verbose")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: mingo serve [-addr ADDR] [-root DIR] [-api API] [-v]")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := &http.Server{
+		Addr: addr,
+		Handler: &mingo.Server{
+			Root:    root,
+			HistDir: api,
+			Verbose: verbose,
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "listening on %s\n", addr)
+	}
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/bobg/errors"
+
+	"github.com/bobg/mingo"
+)
+
+func watchDir(s *mingo.Scanner, dir string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := s.Watch(ctx, dir, time.Second, func(u mingo.WatchUpdate) error {
+		if u.Err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", u.Err)
+		}
+		for _, r := range u.Removed {
+			fmt.Printf("- %s\n", r)
+		}
+		for _, r := range u.Added {
+			fmt.Printf("+ %s\n", r)
+		}
+		fmt.Println(u.Result.Version())
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return errors.Wrap(err, "watching directory")
+}
@@ -0,0 +1,18 @@
+// Command mingovet runs the mingo analyzer under go vet:
+//
+//	go vet -vettool=$(which mingovet) ./...
+//
+// It reports uses of language features and stdlib identifiers
+// that need a newer version of Go than the one declared in go.mod
+// (or the one given with -mingo.target).
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/unitchecker"
+
+	"github.com/bobg/mingo"
+)
+
+func main() {
+	unitchecker.Main(mingo.Analyzer)
+}
@@ -0,0 +1,364 @@
+package mingo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/tools/go/analysis"
+)
+
+// CompatGen generates a compatibility package
+// for the newer stdlib functions that a module uses,
+// and rewrites the module to use it.
+//
+// For each stdlib function introduced in some Go 1.N above Target,
+// the package has a function that forwards to it
+// in a file constrained by //go:build go1.N,
+// and an equivalent fallback implementation
+// in a file constrained by //go:build !go1.N.
+// So newer toolchains use the stdlib,
+// and older ones can still build the module.
+type CompatGen struct {
+	Target  int    // The minor version of Go 1.x that the module should support.
+	PkgDir  string // The directory of the compat package, relative to the module root (default: internal/compat).
+	HistDir string // Find Go stdlib history in this directory (default: $GOROOT/api).
+	Tests   bool   // Rewrite *_test.go files too.
+	Verbose bool   // Be verbose.
+}
+
+// Run generates the compat package for the module in dir and rewrites the module to use it.
+// Functions already in the compat package
+// (from an earlier run)
+// are kept.
+//
+// It returns the module's minimum Go version afterwards,
+// and the uses of stdlib identifiers above the target for which it has no fallback.
+func (g CompatGen) Run(dir string) (Result, []Result, error) {
+	s := &Scanner{
+		HistDir: g.HistDir,
+		Tests:   g.Tests,
+		Verbose: g.Verbose,
+	}
+	if err := s.ensureHistory(); err != nil {
+		return nil, nil, err
+	}
+
+	d := Downgrader{Target: g.Target, Tests: g.Tests, Verbose: g.Verbose}
+	pkgs, err := d.load(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	mod := pkgs[0].Module
+	if mod == nil {
+		return nil, nil, fmt.Errorf("package %s has no module", pkgs[0].PkgPath)
+	}
+
+	pkgDir := g.PkgDir
+	if pkgDir == "" {
+		pkgDir = "internal/compat"
+	}
+	var (
+		compatPath = path.Join(mod.Path, filepath.ToSlash(pkgDir))
+		compatDir  = filepath.Join(mod.Dir, filepath.FromSlash(pkgDir))
+		compatName = path.Base(compatPath)
+	)
+
+	shims, err := existingShims(compatDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		plan = &downgradePlan{
+			edits:   make(map[string][]analysis.TextEdit),
+			imports: make(map[string][]string),
+			files:   make(map[string]typedFile),
+		}
+		unsupported []Result
+		seen        = make(map[token.Position]bool)
+	)
+
+	for _, pkg := range pkgs {
+		if pkg.PkgPath == compatPath {
+			continue
+		}
+
+		var files []*ast.File
+		for _, file := range pkg.Syntax {
+			filename := pkg.Fset.Position(file.Pos()).Filename
+			if isCache, err := isCacheFile(filename); err != nil || isCache {
+				continue
+			}
+			files = append(files, file)
+		}
+		plan.addFiles(pkg, files)
+
+		p := s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+		p.report = true
+		p.target = g.Target
+		if err := p.files(files); err != nil {
+			return nil, nil, errors.Wrapf(err, "scanning package %s", pkg.PkgPath)
+		}
+
+		// Find the stdlib references among the findings.
+		findings := make(map[token.Pos]posResult)
+		for _, f := range p.findings {
+			if f.rule == "" {
+				findings[f.at] = f
+			}
+		}
+
+		for _, file := range files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				sel, ok := node.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				f, ok := findings[sel.Pos()]
+				if !ok || seen[f.pos] {
+					return true
+				}
+				seen[f.pos] = true
+
+				obj := pkg.TypesInfo.Uses[sel.Sel]
+				if obj == nil || obj.Pkg() == nil {
+					unsupported = append(unsupported, f)
+					return true
+				}
+				h, ok := findHelper(obj.Pkg().Path(), obj.Name())
+				if !ok || h.needs > g.Target {
+					unsupported = append(unsupported, f)
+					return true
+				}
+
+				shim := compatShim{helper: h, version: f.version}
+				shims[shim.exportedName()] = shim
+
+				filename := pkg.Fset.Position(sel.Pos()).Filename
+				plan.edits[filename] = append(plan.edits[filename], analysis.TextEdit{
+					Pos:     sel.Pos(),
+					End:     sel.End(),
+					NewText: []byte(compatName + "." + shim.exportedName()),
+				})
+				if !slices.Contains(plan.imports[filename], compatPath) {
+					plan.imports[filename] = append(plan.imports[filename], compatPath)
+				}
+				return false
+			})
+		}
+	}
+
+	slices.SortFunc(unsupported, func(a, b Result) int {
+		return comparePositions(a.(posResult).pos, b.(posResult).pos)
+	})
+
+	if len(shims) > 0 {
+		plan.generated, err = compatSources(compatDir, compatName, g.Target, shims)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "generating compat package in %s", compatDir)
+		}
+	}
+	if err := plan.apply(pkgs[0].Fset, dir, g.Tests); err != nil {
+		return nil, nil, err
+	}
+
+	result, err := s.ScanDir(dir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "rescanning")
+	}
+	return result, unsupported, nil
+}
+
+// Type compatShim is a function (or constant or variable) in the compat package.
+type compatShim struct {
+	helper
+	version int // the minor version of Go 1.x that added the stdlib identifier
+}
+
+// Method exportedName is the name of the shim in the compat package,
+// e.g. StringsCut for strings.Cut.
+func (s compatShim) exportedName() string {
+	name := s.localName()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// Method forward returns a declaration of the shim that forwards to the stdlib.
+func (s compatShim) forward() (string, error) {
+	var (
+		name    = s.exportedName()
+		pkgname = path.Base(s.pkgpath)
+		target  = pkgname + "." + s.name
+	)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", "package p\n"+fmt.Sprintf(s.code, name), 0)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing fallback for %s", target)
+	}
+
+	switch decl := file.Decls[0].(type) {
+	case *ast.GenDecl:
+		return fmt.Sprintf("%s %s = %s", decl.Tok, name, target), nil
+
+	case *ast.FuncDecl:
+		var (
+			args     []string
+			variadic bool
+		)
+		for _, field := range decl.Type.Params.List {
+			for _, id := range field.Names {
+				args = append(args, id.Name)
+			}
+			_, variadic = field.Type.(*ast.Ellipsis)
+		}
+		call := fmt.Sprintf("%s(%s)", target, strings.Join(args, ", "))
+		if variadic {
+			call = call[:len(call)-1] + "...)"
+		}
+		if decl.Type.Results != nil {
+			call = "return " + call
+		}
+
+		decl.Body = nil
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, decl); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s {\n\t%s\n}", buf.String(), call), nil
+	}
+
+	return "", fmt.Errorf("unexpected fallback declaration for %s", target)
+}
+
+// Function existingShims finds the shims already in the compat package in dir, if any.
+func existingShims(dir string) (map[string]compatShim, error) {
+	result := make(map[string]compatShim)
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+		file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", filename)
+		}
+		v := goBuildVersion(file)
+		if v == 0 {
+			continue
+		}
+		for _, h := range helperTable {
+			shim := compatShim{helper: h, version: v}
+			if declares(file, shim.exportedName()) {
+				result[shim.exportedName()] = shim
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Function compatSources generates the files of the compat package in dir,
+// by filename,
+// with a pair of files for each Go version:
+// goN.go forwarding to the stdlib,
+// and goN_fallback.go with the fallbacks.
+// For a target below Go 1.17,
+// which does not understand //go:build lines,
+// the files also get the matching // +build lines.
+func compatSources(dir, pkgname string, target int, shims map[string]compatShim) (map[string][]byte, error) {
+	byVersion := make(map[int][]compatShim)
+	for _, name := range slices.Sorted(maps.Keys(shims)) {
+		shim := shims[name]
+		byVersion[shim.version] = append(byVersion[shim.version], shim)
+	}
+
+	sources := make(map[string][]byte)
+	for v, shims := range byVersion {
+		var (
+			fwdImports      = make(map[string]bool)
+			fallbackImports = make(map[string]bool)
+			fwd, fallback   []string
+		)
+		for _, shim := range shims {
+			decl, err := shim.forward()
+			if err != nil {
+				return nil, err
+			}
+			fwd = append(fwd, decl)
+			fwdImports[shim.pkgpath] = true
+
+			fallback = append(fallback, fmt.Sprintf(shim.code, shim.exportedName()))
+			for _, imp := range shim.imports {
+				fallbackImports[imp] = true
+			}
+		}
+
+		filename := filepath.Join(dir, fmt.Sprintf("go1_%d.go", v))
+		src, err := compatFileSource(filename, fmt.Sprintf("go1.%d", v), target, pkgname, fwdImports, fwd)
+		if err != nil {
+			return nil, err
+		}
+		sources[filename] = src
+
+		filename = filepath.Join(dir, fmt.Sprintf("go1_%d_fallback.go", v))
+		src, err = compatFileSource(filename, fmt.Sprintf("!go1.%d", v), target, pkgname, fallbackImports, fallback)
+		if err != nil {
+			return nil, err
+		}
+		sources[filename] = src
+	}
+
+	return sources, nil
+}
+
+func compatFileSource(filename, expr string, target int, pkgname string, imports map[string]bool, decls []string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	fmt.Fprintln(buf, "// Code generated by mingo compat. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "//go:build %s\n", expr)
+	if target < goBuildLines {
+		x, err := constraint.Parse("//go:build " + expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing build constraint for %s", filename)
+		}
+		lines, err := constraint.PlusBuildLines(x)
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting build constraint for %s", filename)
+		}
+		for _, line := range lines {
+			fmt.Fprintln(buf, line)
+		}
+	}
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "package %s\n", pkgname)
+	writeImports(buf, slices.Sorted(maps.Keys(imports)))
+	for _, decl := range decls {
+		fmt.Fprintln(buf)
+		fmt.Fprintln(buf, decl)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	return src, errors.Wrapf(err, "formatting %s", filename)
+}
@@ -0,0 +1,173 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestCompatGen(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/shims")); err != nil {
+		t.Fatal(err)
+	}
+
+	g := CompatGen{Target: 18}
+	result, unsupported, err := g.Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version() != 20 {
+		t.Errorf("got version %d, want 20 [%s]", result.Version(), result)
+	}
+
+	var got []string
+	for _, r := range unsupported {
+		got = append(got, r.(posResult).desc)
+	}
+	if want := []string{`"errors".Join`}; !slices.Equal(got, want) {
+		t.Errorf("got unsupported %v, want %v", got, want)
+	}
+
+	compatDir := filepath.Join(dir, "internal", "compat")
+	entries, err := os.ReadDir(compatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filenames []string
+	for _, entry := range entries {
+		filenames = append(filenames, entry.Name())
+	}
+	if want := []string{"go1_19.go", "go1_19_fallback.go", "go1_20.go", "go1_20_fallback.go", "go1_21.go", "go1_21_fallback.go"}; !slices.Equal(filenames, want) {
+		t.Errorf("got files %v, want %v", filenames, want)
+	}
+
+	// The rewritten module must still build.
+	conf := &packages.Config{Mode: packages.NeedTypes, Dir: dir}
+	pkgs, err := packages.Load(conf, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			t.Error(e)
+		}
+	}
+
+	// So must the fallbacks.
+	var (
+		fset  = token.NewFileSet()
+		files []*ast.File
+	)
+	for _, filename := range filenames {
+		if !strings.HasSuffix(filename, "_fallback.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(compatDir, filename), nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	tconf := &types.Config{Importer: importer.Default(), GoVersion: "go1.18"}
+	if _, err := tconf.Check("example.com/shims/internal/compat", fset, files, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Running again changes nothing.
+	if _, _, err := g.Run(dir); err != nil {
+		t.Fatal(err)
+	}
+	entries2, err := os.ReadDir(compatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries2) != len(entries) {
+		t.Errorf("got %d files on second run, want %d", len(entries2), len(entries))
+	}
+}
+
+func TestCompatGenNothingWritten(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/shims")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The compat import will collide with this.
+	if err := os.WriteFile(filepath.Join(dir, "collide.go"), []byte("package shims\n\nvar compat = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.ReadFile(filepath.Join(dir, "shims.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := CompatGen{Target: 18}
+	if _, _, err := g.Run(dir); err == nil {
+		t.Fatal("got no error for a rewrite that does not build")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "internal", "compat")); !os.IsNotExist(err) {
+		t.Errorf("compat package written (stat error %v)", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "shims.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(orig) {
+		t.Error("shims.go rewritten")
+	}
+}
+
+func TestCompatGenPlusBuild(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/shims")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Go 1.16 does not understand //go:build lines.
+	g := CompatGen{Target: 16}
+	if _, _, err := g.Run(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	compatDir := filepath.Join(dir, "internal", "compat")
+	entries, err := os.ReadDir(compatDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("no compat files")
+	}
+	for _, entry := range entries {
+		filename := filepath.Join(compatDir, entry.Name())
+		file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var goBuild, plusBuild []string
+		for _, c := range buildConstraints(file) {
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if constraint.IsGoBuild(c.Text) {
+				goBuild = append(goBuild, expr.String())
+			} else {
+				plusBuild = append(plusBuild, expr.String())
+			}
+		}
+		if len(goBuild) != 1 || !slices.Equal(plusBuild, goBuild) {
+			t.Errorf("%s: got //go:build %v and // +build %v, want one of each with the same meaning", entry.Name(), goBuild, plusBuild)
+		}
+	}
+}
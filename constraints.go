@@ -0,0 +1,207 @@
+package mingo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// Build-constraint names that older versions of Go do not recognize,
+// mapped to the version of Go that added each one.
+// To an older toolchain,
+// a filename suffix like _wasip1 is no constraint at all,
+// and a //go:build tag like unix is never satisfied.
+var buildTagVersions = map[string]int{
+	// GOOS values.
+	"android": 4,
+	"js":      11,
+	"aix":     12,
+	"illumos": 13,
+	"ios":     16,
+	"wasip1":  21,
+
+	// GOARCH values.
+	"arm64":    5,
+	"ppc64":    5,
+	"ppc64le":  5,
+	"mips64":   6,
+	"mips64le": 6,
+	"s390x":    7,
+	"mips":     8,
+	"mipsle":   8,
+	"wasm":     11,
+	"loong64":  19,
+
+	// Other keywords.
+	"unix": 19,
+}
+
+// The GOOS and GOARCH values that go/build recognizes in filenames
+// (from its syslist.go).
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true,
+		"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+		"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true,
+		"riscv64": true, "s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// Method buildTags reports the GOOS, GOARCH, and other build-constraint names
+// in file's name and build constraint
+// that need a newer version of Go.
+// Bool result tells whether the max known Go version has been reached.
+func (p *pkgScanner) buildTags(file *ast.File, filename string) bool {
+	// Filename suffixes, as in go/build:
+	// name_GOOS, name_GOARCH, or name_GOOS_GOARCH, with an optional _test.
+	// The next-to-last element is a GOOS only when the last is a GOARCH,
+	// so x_ios_helper.go has no constraint.
+	name, _, _ := strings.Cut(filepath.Base(filename), ".")
+	if _, rest, ok := strings.Cut(name, "_"); ok {
+		l := strings.Split(rest, "_")
+		if n := len(l); n > 0 && l[n-1] == "test" {
+			l = l[:n-1]
+		}
+		var elems []string
+		switch n := len(l); {
+		case n >= 2 && knownOS[l[n-2]] && knownArch[l[n-1]]:
+			elems = l[n-2:]
+		case n >= 1 && (knownOS[l[n-1]] || knownArch[l[n-1]]):
+			elems = l[n-1:]
+		}
+		for _, elem := range elems {
+			if v := buildTagVersions[elem]; v > 0 {
+				if p.result(posResult{
+					version: v,
+					pos:     p.fset.Position(file.Package),
+					at:      file.Package,
+					desc:    fmt.Sprintf("%q in filename", elem),
+					rule:    "build-tag",
+				}) {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, c := range buildConstraints(file) {
+		expr, err := constraint.Parse(c.Text)
+		if err != nil {
+			continue
+		}
+		var isMax bool
+		expr.Eval(func(tag string) bool {
+			if v := buildTagVersions[tag]; v > 0 && !isMax {
+				isMax = p.result(posResult{
+					version: v,
+					pos:     p.fset.Position(c.Pos()),
+					at:      c.Pos(),
+					desc:    fmt.Sprintf("%q in build constraint", tag),
+					rule:    "build-tag",
+				})
+			}
+			return false
+		})
+		if isMax {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Function buildConstraints returns the //go:build and // +build lines of file,
+// which precede its package clause.
+func buildConstraints(file *ast.File) []*ast.Comment {
+	var result []*ast.Comment
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if constraint.IsGoBuild(c.Text) || constraint.IsPlusBuild(c.Text) {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+// Go 1.17 introduced //go:build lines.
+const goBuildLines = 17
+
+// Versions before Go 1.17 need // +build lines with the same meaning,
+// which gofmt adds.
+func matchGoBuild(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	comment, ok := node.(*ast.Comment)
+	if !ok || !constraint.IsGoBuild(comment.Text) || comment.Pos() >= c.file.Package {
+		return token.NoPos, false
+	}
+	goBuild, err := constraint.Parse(comment.Text)
+	if err != nil {
+		return token.NoPos, false
+	}
+
+	var plusBuild constraint.Expr
+	for _, c := range buildConstraints(c.file) {
+		if !constraint.IsPlusBuild(c.Text) {
+			continue
+		}
+		expr, err := constraint.Parse(c.Text)
+		if err != nil {
+			continue
+		}
+		if plusBuild == nil {
+			plusBuild = expr
+		} else {
+			plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+		}
+	}
+	if plusBuild == nil || !equivalentConstraints(goBuild, plusBuild) {
+		return comment.Pos(), true
+	}
+	return token.NoPos, false
+}
+
+// Function equivalentConstraints tells whether a and b are satisfied by the same sets of tags.
+// With too many tags to try every combination,
+// it gives them the benefit of the doubt.
+func equivalentConstraints(a, b constraint.Expr) bool {
+	var tags []string
+	seen := make(map[string]bool)
+	collect := func(tag string) bool {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		return false
+	}
+	a.Eval(collect)
+	b.Eval(collect)
+
+	if len(tags) > 16 {
+		return true
+	}
+	for bits := range 1 << len(tags) {
+		ok := func(tag string) bool {
+			for i, t := range tags {
+				if t == tag {
+					return bits&(1<<i) != 0
+				}
+			}
+			return false
+		}
+		if a.Eval(ok) != b.Eval(ok) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildTags(t *testing.T) {
+	cases := []struct {
+		filename, header string
+		want             int
+	}{
+		{"x.go", "", 0},
+		{"x_wasip1.go", "", 21},
+		{"x_wasip1_wasm_test.go", "", 21},
+		{"x_linux_loong64.go", "", 19},
+		{"wasip1.go", "", 0},
+		{"x_ios.go", "", 16},
+		{"x_ios_helper.go", "", 0}, // ios is a GOOS only before a GOARCH
+		{"x_ios_amd64.go", "", 16},
+		{"x_ios_arm64.go", "", 16},
+		{"x_wasip1_helper_test.go", "", 0},
+		{"x_unix.go", "", 0}, // unix is not a GOOS
+		{"x_loong64_linux.go", "", 0},
+		{"x.go", "//go:build unix\n// +build unix\n", 19},
+		{"x.go", "//go:build linux || wasip1\n// +build linux wasip1\n", 21},
+		{"x.go", "//go:build linux && amd64\n// +build linux,amd64\n", 0},
+		{"x.go", "//go:build linux && amd64\n// +build linux\n// +build amd64\n", 0},
+		{"x.go", "//go:build linux && amd64\n", 17},
+		{"x.go", "//go:build linux && amd64\n// +build linux amd64\n", 17},
+		{"x.go", "//go:build go1.21 && wasip1\n", 0}, // the file is only for Go 1.21 and later
+	}
+
+	var s Scanner
+	if err := s.ensureHistory(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.filename+" "+tc.header, func(t *testing.T) {
+			fset := token.NewFileSet()
+			filename := filepath.Join(t.TempDir(), tc.filename)
+			file, err := parser.ParseFile(fset, filename, tc.header+"\npackage p\n", parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Types: make(map[ast.Expr]types.TypeAndValue),
+				Defs:  make(map[*ast.Ident]types.Object),
+				Uses:  make(map[*ast.Ident]types.Object),
+			}
+			pkg, err := new(types.Config).Check("p", fset, []*ast.File{file}, info)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := s.newPkgScanner(pkg, fset, info)
+			if err := p.files([]*ast.File{file}); err != nil {
+				t.Fatal(err)
+			}
+			if got := p.res.Version(); got != tc.want {
+				t.Errorf("got %s, want %d", p.res, tc.want)
+			}
+		})
+	}
+}
@@ -22,26 +22,6 @@ func (p *pkgScanner) funcDecl(decl *ast.FuncDecl) (bool, error) {
 		return isMax, errors.Wrapf(err, "scanning receiver for func %s", decl.Name.Name)
 	}
 
-	// Generics are supported in Go 1.18 and later.
-	if decl.Type.TypeParams != nil && len(decl.Type.TypeParams.List) > 0 {
-		p.result(posResult{
-			version: 18,
-			pos:     p.fset.Position(decl.Pos()),
-			desc:    "generic func decl",
-		})
-
-		if decl.Recv != nil && len(decl.Recv.List) > 0 {
-			res := posResult{
-				version: 27,
-				pos:     p.fset.Position(decl.Pos()),
-				desc:    "generic method",
-			}
-			if p.result(res) {
-				return true, nil
-			}
-		}
-	}
-
 	if isMax, err := p.fieldList(decl.Type.Params); err != nil || isMax {
 		return isMax, errors.Wrapf(err, "scanning params for func %s", decl.Name.Name)
 	}
@@ -102,69 +82,5 @@ func (p *pkgScanner) valueSpec(spec *ast.ValueSpec) (bool, error) {
 }
 
 func (p *pkgScanner) typeSpec(spec *ast.TypeSpec) (bool, error) {
-	var generic bool
-
-	if spec.TypeParams != nil && len(spec.TypeParams.List) > 0 {
-		generic = true
-		p.result(posResult{
-			version: 18,
-			pos:     p.fset.Position(spec.Pos()),
-			desc:    "generic type decl",
-		})
-
-		v := recursiveTypeParamVisitor{name: spec.Name}
-		ast.Walk(&v, spec.TypeParams)
-		if v.found {
-			isMax := p.result(posResult{
-				version: 26,
-				pos:     p.fset.Position(spec.Pos()),
-				desc:    "recursive type parameter",
-			})
-			if isMax {
-				return true, nil
-			}
-		}
-	}
-
-	if spec.Assign.IsValid() {
-		if generic {
-			p.result(posResult{
-				version: 24,
-				pos:     p.fset.Position(spec.Pos()),
-				desc:    "generic type alias",
-			})
-		} else {
-			p.result(posResult{
-				version: 9,
-				pos:     p.fset.Position(spec.Pos()),
-				desc:    "type alias",
-			})
-		}
-	}
-
-	// Generics are supported in Go 1.18 and later.
-	if spec.TypeParams != nil && len(spec.TypeParams.List) > 0 {
-		p.result(posResult{
-			version: 18,
-			pos:     p.fset.Position(spec.Pos()),
-			desc:    "generic type decl",
-		})
-	}
 	return p.expr(spec.Type)
 }
-
-type recursiveTypeParamVisitor struct {
-	name  *ast.Ident
-	found bool
-}
-
-func (v *recursiveTypeParamVisitor) Visit(node ast.Node) ast.Visitor {
-	if v.found {
-		return nil
-	}
-	if ident, ok := node.(*ast.Ident); ok && ident.Name == v.name.Name {
-		v.found = true
-		return nil
-	}
-	return v
-}
@@ -1,19 +1,18 @@
 package mingo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
-	"strconv"
-	"strings"
 
 	"github.com/bobg/errors"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
 )
 
-func (s *Scanner) scanDeps(gomodPath string) error {
+func (st *scanState) scanDeps(ctx context.Context, gomodPath string) error {
 	gomodBytes, err := os.ReadFile(gomodPath)
 	if err != nil {
 		return errors.Wrapf(err, "reading go.mod at %s", gomodPath)
@@ -25,10 +24,13 @@ func (s *Scanner) scanDeps(gomodPath string) error {
 	}
 
 	for _, r := range f.Require {
-		if r.Indirect && !s.Indirect {
+		if r.Indirect && !st.s.Indirect {
 			continue
 		}
-		if err := s.scanDep(r.Mod); err != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := st.scanDep(ctx, r.Mod); err != nil {
 			return errors.Wrapf(err, "scanning dep %s", r.Mod.Path)
 		}
 	}
@@ -41,15 +43,15 @@ type modDownload struct {
 }
 
 type depScanner interface {
-	scan(modpath, version string) (modDownload, error)
+	scan(ctx context.Context, modpath, version string) (modDownload, error)
 }
 
 type realDepScanner struct{}
 
-func (s realDepScanner) scan(modpath, version string) (modDownload, error) {
+func (s realDepScanner) scan(ctx context.Context, modpath, version string) (modDownload, error) {
 	var result modDownload
 
-	cmd := exec.Command("go", "mod", "download", "-json", modpath+"@"+version)
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", modpath+"@"+version)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return result, errors.Wrapf(err, "creating stdout pipe for download of %s", modpath)
@@ -68,13 +70,13 @@ func (s realDepScanner) scan(modpath, version string) (modDownload, error) {
 	return result, errors.Wrapf(err, "waiting for download of %s", modpath)
 }
 
-func (s *Scanner) scanDep(mv module.Version) error {
-	scanner := s.depScanner
+func (st *scanState) scanDep(ctx context.Context, mv module.Version) error {
+	scanner := st.s.depScanner
 	if scanner == nil {
 		scanner = realDepScanner{}
 	}
 
-	download, err := scanner.scan(mv.Path, mv.Version)
+	download, err := scanner.scan(ctx, mv.Path, mv.Version)
 	if err != nil {
 		return errors.Wrapf(err, "scanning %s@%s", mv.Path, mv.Version)
 	}
@@ -91,16 +93,12 @@ func (s *Scanner) scanDep(mv module.Version) error {
 		// Probably a pre-Go 1.11 module.
 		return nil
 	}
-	parts := strings.SplitN(parsed.Go.Version, ".", 3)
-	if len(parts) < 2 {
-		return fmt.Errorf("go.mod of %s has invalid go version %s", mv.Path, parsed.Go.Version)
-	}
-	minor, err := strconv.Atoi(parts[1])
+	minor, err := parseGoVersion(parsed.Go.Version)
 	if err != nil {
-		return fmt.Errorf("go.mod of %s has invalid go version %s", mv.Path, parsed.Go.Version)
+		return errors.Wrapf(err, "in go.mod of %s", mv.Path)
 	}
 
-	s.result(depResult{
+	st.result(depResult{
 		version:    minor,
 		modpath:    mv.Path,
 		modversion: mv.Version,
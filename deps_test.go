@@ -1,6 +1,7 @@
 package mingo
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -11,25 +12,22 @@ func TestScanDeps(t *testing.T) {
 		depScanner: mockDepScanner{
 			"foo.bar/baz@v1.2.3": "testdata/foobar.go.mod",
 		},
-		Result: intResult(0),
 	}
 	if err := s.ensureHistory(); err != nil {
 		t.Fatal(err)
 	}
-	if err := s.scanDeps("testdata/go.mod"); err != nil {
+	st := s.newScanState()
+	if err := st.scanDeps(context.Background(), "testdata/go.mod"); err != nil {
 		t.Fatal(err)
 	}
-	if s.Result == nil {
-		t.Fatal("nil result")
-	}
-	if s.Result.Version() != 16 {
-		t.Errorf("got %d, want 16", s.Result.Version())
+	if st.res.Version() != 16 {
+		t.Errorf("got %d, want 16", st.res.Version())
 	}
 }
 
 type mockDepScanner map[string]string
 
-func (m mockDepScanner) scan(modpath, version string) (modDownload, error) {
+func (m mockDepScanner) scan(_ context.Context, modpath, version string) (modDownload, error) {
 	str := modpath + "@" + version
 	v, ok := m[str]
 	if !ok {
@@ -0,0 +1,257 @@
+package mingo
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Type depRef is a reference in the scanned code
+// to an exported identifier in a non-stdlib package.
+// The fields are as for [history.lookup].
+type depRef struct {
+	pkgpath, id, typ string
+}
+
+// Method depRef records a reference for [Scanner.DepVersions].
+func (p *pkgScanner) depRef(pkgpath, id, typ string, res posResult) {
+	if !p.s.DepVersions || isStdlib(pkgpath) {
+		return
+	}
+	ref := depRef{pkgpath: pkgpath, id: id, typ: typ}
+	if _, ok := p.refs[ref]; !ok {
+		p.refs[ref] = res
+	}
+}
+
+// Function isStdlib tells whether pkgpath looks like a stdlib package,
+// i.e. its first path element contains no dot.
+func isStdlib(pkgpath string) bool {
+	first, _, _ := strings.Cut(pkgpath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Method depVersions computes, for each module required by the go.mod at gomodPath,
+// the oldest version in the module cache that provides every identifier
+// the scanned code uses from it.
+// Results go into st.modResults,
+// except for modules that already have results there
+// (from [Scanner.ModHist]).
+func (st *scanState) depVersions(ctx context.Context, gomodPath string) error {
+	gomodBytes, err := os.ReadFile(gomodPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading go.mod at %s", gomodPath)
+	}
+
+	f, err := modfile.ParseLax(gomodPath, gomodBytes, nil)
+	if err != nil {
+		return errors.Wrapf(err, "parsing go.mod at %s", gomodPath)
+	}
+
+	byMod := make(map[string]map[depRef]posResult)
+	for ref, res := range st.refs {
+		r := requireFor(f.Require, ref.pkgpath)
+		if r == nil {
+			continue
+		}
+		refs, ok := byMod[r.Mod.Path]
+		if !ok {
+			refs = make(map[depRef]posResult)
+			byMod[r.Mod.Path] = refs
+		}
+		refs[ref] = res
+	}
+
+	for _, r := range f.Require {
+		refs, ok := byMod[r.Mod.Path]
+		if !ok {
+			continue
+		}
+		if _, ok := st.modResults[r.Mod.Path]; ok {
+			continue
+		}
+		if err := st.depVersion(ctx, r.Mod, refs); err != nil {
+			return errors.Wrapf(err, "computing minimum version of %s", r.Mod.Path)
+		}
+	}
+
+	return nil
+}
+
+// Function requireFor finds the require directive for the module containing pkgpath.
+// When module paths are nested,
+// the longest match wins.
+func requireFor(requires []*modfile.Require, pkgpath string) *modfile.Require {
+	var result *modfile.Require
+	for _, r := range requires {
+		if pkgpath != r.Mod.Path && !strings.HasPrefix(pkgpath, r.Mod.Path+"/") {
+			continue
+		}
+		if result == nil || len(r.Mod.Path) > len(result.Mod.Path) {
+			result = r
+		}
+	}
+	return result
+}
+
+// Error errUnknownAPI wraps the errors of versions whose API cannot be computed,
+// e.g. because they do not build with the current toolchain.
+var errUnknownAPI = errors.New("unknown API")
+
+// Method depVersion binary-searches the released versions of mv.Path in the module cache,
+// up to and including mv.Version,
+// for the oldest one that provides all of refs.
+// This assumes that a module's API only grows over time.
+//
+// A version that fails to load says nothing about which identifiers it provides,
+// so if the search needs one,
+// it stops without a result for the module.
+func (st *scanState) depVersion(ctx context.Context, mv module.Version, refs map[depRef]posResult) error {
+	src := modCacheSource{modpath: mv.Path}
+
+	versions, err := src.versions()
+	if err != nil {
+		st.s.verbosef("skipping %s: %s", mv.Path, err)
+		return nil
+	}
+	versions = slices.DeleteFunc(versions, func(v string) bool {
+		return semver.Compare(v, mv.Version) > 0
+	})
+	if !slices.Contains(versions, mv.Version) {
+		versions = append(versions, mv.Version)
+	}
+
+	dir, _, err := src.dir(mv.Version)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		// E.g. a replaced module.
+		st.s.verbosef("skipping %s: %s", mv.Path, err)
+		return nil
+	}
+
+	apis := make(map[string]*history)
+	api := func(version string) (*history, error) {
+		if h, ok := apis[version]; ok {
+			return h, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		dir, cleanup, err := src.dir(version)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		st.s.verbosef("computing API of %s@%s", mv.Path, version)
+		lines, err := ModuleAPI(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%w of %s@%s: %w", errUnknownAPI, mv.Path, version, err)
+		}
+		h := &history{pkgs: make(map[string]pkgHistory)}
+		if err := readHistLines(h, strings.NewReader(strings.Join(lines, "\n")), 1); err != nil {
+			return nil, errors.Wrapf(err, "parsing API of %s@%s", mv.Path, version)
+		}
+		apis[version] = h
+		return h, nil
+	}
+
+	missing := func(version string) ([]depRef, error) {
+		h, err := api(version)
+		if err != nil {
+			return nil, err
+		}
+		var result []depRef
+		for ref := range refs {
+			if h.lookup(ref.pkgpath, ref.id, ref.typ) == 0 {
+				result = append(result, ref)
+			}
+		}
+		return result, nil
+	}
+
+	skip := func(err error) error {
+		if errors.Is(err, errUnknownAPI) {
+			st.s.verbosef("skipping %s: %s", mv.Path, err)
+			return nil
+		}
+		return err
+	}
+
+	// Ignore references that even the required version doesn't appear to provide
+	// (e.g. promoted fields, which API files do not list).
+	unresolved, err := missing(mv.Version)
+	if err != nil {
+		return skip(err)
+	}
+	for _, ref := range unresolved {
+		delete(refs, ref)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var searchErr error
+	idx := sort.Search(len(versions), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		m, err := missing(versions[i])
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return len(m) == 0
+	})
+	if searchErr != nil {
+		return skip(searchErr)
+	}
+
+	// Find a reference that the next-older version lacks, to explain the result.
+	why := slices.Collect(maps.Keys(refs))
+	if idx > 0 {
+		m, err := missing(versions[idx-1])
+		if err != nil {
+			return skip(err)
+		}
+		if len(m) > 0 {
+			why = m
+		}
+	}
+	first := slices.MinFunc(why, func(a, b depRef) int {
+		return comparePositions(refs[a].pos, refs[b].pos)
+	})
+
+	r := ModResult{
+		ModPath: mv.Path,
+		Version: versions[idx],
+		Pos:     refs[first].pos,
+		Desc:    refs[first].desc,
+	}
+	st.modResults[mv.Path] = r
+	st.s.verbosef("%s", r)
+
+	return nil
+}
+
+func comparePositions(a, b token.Position) int {
+	if c := strings.Compare(a.Filename, b.Filename); c != 0 {
+		return c
+	}
+	if c := a.Line - b.Line; c != 0 {
+		return c
+	}
+	return a.Column - b.Column
+}
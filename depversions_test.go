@@ -0,0 +1,80 @@
+package mingo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDepVersions(t *testing.T) {
+	// Populate a fake module cache with three versions of example.com/lib.
+	fakeModCache(t, map[string]string{
+		"v1.0.0": "package lib\n\ntype T struct{ X int }\n\nfunc (T) M() {}\n\nfunc Foo() int { return 1 }\n",
+		"v1.1.0": "package lib\n\ntype T struct{ X, Y int }\n\nfunc (T) M() {}\n\nfunc Foo() int { return 1 }\n\nfunc Bar() int { return 2 }\n",
+		"v1.2.0": libV120,
+	})
+
+	s := Scanner{DepVersions: true}
+	res, err := s.ScanDirContext(context.Background(), "testdata/modhist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modres, ok := res.ModResults["example.com/lib"]
+	if !ok {
+		t.Fatal("no result for example.com/lib")
+	}
+	if modres.Version != "v1.1.0" {
+		t.Errorf("got example.com/lib version %s (%s), want v1.1.0", modres.Version, modres)
+	}
+	if modres.Desc != `"example.com/lib".Bar` {
+		t.Errorf("got explanation %q, want %q", modres.Desc, `"example.com/lib".Bar`)
+	}
+}
+
+func TestDepVersionsLoadError(t *testing.T) {
+	// The search probes v1.1.0 first.
+	// That it does not build says nothing about whether it has Bar,
+	// so there is no result for the module (and no error).
+	fakeModCache(t, map[string]string{
+		"v1.0.0": "package lib\n\ntype T struct{ X int }\n\nfunc (T) M() {}\n\nfunc Foo() int { return 1 }\n",
+		"v1.1.0": "package lib\n\ntype T struct{ X, Y int }\n\nfunc (T) M() {}\n\nfunc Foo() int { return undefined }\n\nfunc Bar() int { return 2 }\n",
+		"v1.2.0": libV120,
+	})
+
+	s := Scanner{DepVersions: true}
+	res, err := s.ScanDirContext(context.Background(), "testdata/modhist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modres, ok := res.ModResults["example.com/lib"]; ok {
+		t.Errorf("got result %s for example.com/lib, want none", modres)
+	}
+}
+
+const libV120 = "package lib\n\ntype T struct{ X, Y int }\n\nfunc (T) M() {}\n\nfunc (T) N() {}\n\nfunc Foo() int { return 1 }\n\nfunc Bar() int { return 2 }\n\nfunc Baz() int { return 3 }\n"
+
+// Function fakeModCache populates a fake module cache with the given versions of example.com/lib,
+// each with a single source file,
+// and points the go command at it.
+func fakeModCache(t *testing.T, libVersions map[string]string) {
+	t.Helper()
+
+	modcache := t.TempDir()
+	for version, src := range libVersions {
+		dir := filepath.Join(modcache, "example.com", "lib@"+version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/lib\n\ngo 1.18\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "lib.go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("GOMODCACHE", modcache)
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+}
@@ -0,0 +1,176 @@
+package mingo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// These are the Match functions for the rules about compiler directives
+// (//go:embed and so on)
+// and the #cgo lines in cgo preambles.
+// They match *ast.Comment nodes.
+
+func matchDirective(name string) func(*RuleContext, ast.Node) (token.Pos, bool) {
+	return func(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+		if _, ok := directiveArgs(node, name); !ok {
+			return token.NoPos, false
+		}
+		return node.Pos(), true
+	}
+}
+
+func matchEmbedAll(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	args, ok := directiveArgs(node, "embed")
+	if !ok {
+		return token.NoPos, false
+	}
+	for _, arg := range args {
+		if unquoted, err := strconv.Unquote(arg); err == nil {
+			arg = unquoted
+		}
+		if strings.HasPrefix(arg, "all:") {
+			return node.Pos(), true
+		}
+	}
+	return token.NoPos, false
+}
+
+// Function directiveArgs returns the arguments of a //go:name directive,
+// if node is one.
+func directiveArgs(node ast.Node, name string) ([]string, bool) {
+	comment, ok := node.(*ast.Comment)
+	if !ok {
+		return nil, false
+	}
+	rest, ok := strings.CutPrefix(comment.Text, "//go:"+name)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return nil, false
+	}
+	return strings.Fields(rest), true
+}
+
+// Function matchCgoFlag returns the Match function for a "#cgo NAME" line in a cgo preamble.
+func matchCgoFlag(names ...string) func(*RuleContext, ast.Node) (token.Pos, bool) {
+	return func(c *RuleContext, node ast.Node) (token.Pos, bool) {
+		comment, ok := node.(*ast.Comment)
+		if !ok || !strings.Contains(comment.Text, "#cgo") || !c.isCgoPreamble(comment) {
+			return token.NoPos, false
+		}
+		offset := 0
+		for line := range strings.Lines(comment.Text) {
+			fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), "//"))
+			if len(fields) >= 2 && fields[0] == "#cgo" {
+				for _, name := range names {
+					if fields[1] == name {
+						return comment.Pos() + token.Pos(offset+strings.Index(line, "#cgo")), true
+					}
+				}
+			}
+			offset += len(line)
+		}
+		return token.NoPos, false
+	}
+}
+
+// Method isCgoPreamble tells whether comment is part of the preamble of an import "C".
+// In a file that cmd/cgo has already processed
+// (as [golang.org/x/tools/go/packages] presents cgo files),
+// the import is gone,
+// so any comment qualifies.
+func (c *RuleContext) isCgoPreamble(comment *ast.Comment) bool {
+	if isCgoGenerated(c.file) {
+		return true
+	}
+	for _, decl := range c.file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ImportSpec)
+			if spec.Path.Value != `"C"` {
+				continue
+			}
+			for _, doc := range []*ast.CommentGroup{gen.Doc, spec.Doc} {
+				if doc != nil && doc.Pos() <= comment.Pos() && comment.End() <= doc.End() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isCgoGenerated(file *ast.File) bool {
+	return len(file.Comments) > 0 && file.Comments[0].List[0].Text == "// Code generated by cmd/cgo; DO NOT EDIT."
+}
+
+// As of Go 1.23,
+// the linker rejects "pull-style" //go:linkname references
+// to stdlib symbols that are not explicitly marked for them.
+// The directive names a local declaration with no body
+// and the symbol in another package that provides it.
+// Mingo cannot tell which symbols are marked,
+// so it warns about every such reference into the stdlib.
+const linknameRestricted = 23
+
+// Method linknames reports the pull-style //go:linkname references to the stdlib in file
+// as warnings.
+func (p *pkgScanner) linknames(file *ast.File) {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			args, ok := directiveArgs(comment, "linkname")
+			if !ok || len(args) != 2 {
+				continue
+			}
+			local, target := args[0], args[1]
+
+			slash := strings.LastIndex(target, "/")
+			dot := strings.Index(target[slash+1:], ".")
+			if dot < 0 {
+				continue
+			}
+			pkgpath := target[:slash+1+dot]
+			if !isStdlib(pkgpath) || (p.pkg != nil && pkgpath == p.pkg.Path()) || !isBodiless(file, local) {
+				continue
+			}
+
+			p.warnings = append(p.warnings, posResult{
+				version: linknameRestricted,
+				pos:     p.fset.Position(comment.Pos()),
+				at:      comment.Pos(),
+				desc:    fmt.Sprintf("pull-style //go:linkname reference to %s, restricted as of Go 1.%d", target, linknameRestricted),
+				rule:    "go-linkname",
+			})
+		}
+	}
+}
+
+// Function isBodiless tells whether name is declared in file
+// as a function with no body or as a variable,
+// i.e. something whose definition comes from elsewhere.
+func isBodiless(file *ast.File, name string) bool {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name == name {
+				return decl.Body == nil
+			}
+		case *ast.GenDecl:
+			if decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				for _, ident := range spec.(*ast.ValueSpec).Names {
+					if ident.Name == name {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDirectives(t *testing.T) {
+	const src = `package p
+
+/*
+#cgo CFLAGS: -O2
+#cgo noescape f
+#cgo nocallback g
+static void f(void) {}
+static void g(void) {}
+*/
+import "C"
+
+import (
+	"embed"
+	_ "unsafe"
+)
+
+//go:embed a.txt
+var a string
+
+//go:embed "all:dir"
+var dir embed.FS
+
+//go:debug panicnil=1
+
+//go:wasmimport env h
+func h()
+
+//go:wasmexport k
+func k() {}
+
+//go:linkname fastrand runtime.fastrand
+func fastrand() uint32
+
+//go:linkname pushed example.com/other.pushed
+func pushed() {}
+
+//go:noinline
+func n() {}
+
+// #cgo noescape not_a_preamble
+var _ = 0
+`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(t.TempDir(), "p.go"), src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := &types.Config{
+		FakeImportC: true,
+		Importer:    importerFunc(func(path string) (*types.Package, error) { return types.NewPackage(path, path), nil }),
+		Error:       func(error) {}, // e.g. the undefined embed.FS
+	}
+	pkg, _ := conf.Check("p", fset, []*ast.File{file}, info)
+
+	var s Scanner
+	if err := s.ensureHistory(); err != nil {
+		t.Fatal(err)
+	}
+	p := s.newPkgScanner(pkg, fset, info)
+	p.report = true
+	if err := p.files([]*ast.File{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	type finding struct {
+		line int
+		rule string
+	}
+	var got []finding
+	for _, f := range p.findings {
+		switch f.rule {
+		case "go-embed", "go-embed-all", "go-debug", "go-wasmimport", "go-wasmexport", "cgo-noescape-nocallback":
+			got = append(got, finding{f.pos.Line, f.rule})
+		}
+	}
+	want := []finding{
+		{5, "cgo-noescape-nocallback"}, // the first in the preamble
+		{17, "go-embed"},
+		{20, "go-embed"},
+		{20, "go-embed-all"},
+		{23, "go-debug"},
+		{25, "go-wasmimport"},
+		{28, "go-wasmexport"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got findings %v, want %v", got, want)
+	}
+
+	if len(p.warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(p.warnings))
+	}
+	if w := p.warnings[0]; w.pos.Line != 31 || w.version != 23 {
+		t.Errorf("got warning %s, want one at line 31 for Go 1.23", w)
+	}
+}
@@ -0,0 +1,650 @@
+package mingo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// Downgrader rewrites the code in a module so that it needs no newer version of Go than Target.
+// Uses of newer stdlib functions are replaced with calls to generated helpers in the same package,
+// and newer language features are rewritten using the Fix functions of the rules that detect them
+// (see [Rule]).
+type Downgrader struct {
+	Target  int    // The minor version of Go 1.x to rewrite for.
+	HistDir string // Find Go stdlib history in this directory (default: $GOROOT/api).
+	Tests   bool   // Rewrite *_test.go files too.
+	Verbose bool   // Be verbose.
+
+	// Deps, if true, includes the module's dependencies
+	// in the final rescan that decides whether go.mod can be lowered,
+	// as with [Scanner.Deps] (which downloads their go.mod files).
+	// Otherwise only the module itself counts.
+	Deps     bool
+	Indirect bool // With Deps, include indirect dependencies.
+}
+
+// Names of the files holding the generated helpers in each package.
+const (
+	helpersFile     = "mingo_helpers.go"
+	helpersTestFile = "mingo_helpers_test.go" // for external test packages
+)
+
+// Run rewrites the module in dir,
+// rescanning it after each round of rewrites
+// until no more can be made.
+// If the result is at or below the target,
+// it also lowers the go directive in go.mod to match.
+//
+// It returns the module's minimum Go version after rewriting,
+// and the uses of features above the target that it could not rewrite.
+func (d Downgrader) Run(dir string) (Result, []Result, error) {
+	s := &Scanner{
+		HistDir:  d.HistDir,
+		Tests:    d.Tests,
+		Verbose:  d.Verbose,
+		Deps:     d.Deps,
+		Indirect: d.Indirect,
+	}
+	if err := s.ensureHistory(); err != nil {
+		return nil, nil, err
+	}
+
+	const maxRounds = 10
+
+	var unrewritten []Result
+
+	for round := 1; ; round++ {
+		if round > maxRounds {
+			return nil, nil, fmt.Errorf("still rewriting after %d rounds", maxRounds)
+		}
+
+		pkgs, err := d.load(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		plan, err := d.plan(s, pkgs)
+		if err != nil {
+			return nil, nil, err
+		}
+		unrewritten = plan.unrewritten
+
+		if len(plan.edits) == 0 {
+			break
+		}
+
+		d.verbosef("round %d: rewriting %d file(s)", round, len(plan.edits))
+
+		if err := plan.apply(pkgs[0].Fset, dir, d.Tests); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	result, err := s.ScanDir(dir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "rescanning")
+	}
+
+	if result.Version() <= d.Target {
+		if err := d.lowerGoMod(dir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return result, unrewritten, nil
+}
+
+func (d Downgrader) load(dir string) ([]*packages.Package, error) {
+	conf := &packages.Config{
+		Mode:  Mode,
+		Dir:   dir,
+		Tests: d.Tests,
+	}
+	pkgs, err := packages.Load(conf, "./...")
+	if err != nil {
+		return nil, errors.Wrap(err, "loading packages")
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages in %s", dir)
+	}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			err = errors.Join(err, LoadError{Err: e, Path: pkg.PkgPath})
+		}
+	}
+	return pkgs, errors.Wrap(err, "loading package(s)")
+}
+
+// Type downgradePlan is one round of rewrites.
+type downgradePlan struct {
+	edits       map[string][]analysis.TextEdit // by filename
+	imports     map[string][]string            // by filename, imports to add (for the compat package, see [CompatGen])
+	files       map[string]typedFile           // by filename, the files with edits as they were loaded
+	helpers     map[helperFileKey]map[string]helper
+	generated   map[string][]byte // by filename, whole files to write (the compat package, see [CompatGen])
+	unrewritten []Result
+}
+
+// Type typedFile is a file together with the type information for its package.
+type typedFile struct {
+	file *ast.File
+	info *types.Info
+}
+
+// Method addFiles records the files of pkg,
+// for deciding after the edits which of their imports are unused.
+func (plan *downgradePlan) addFiles(pkg *packages.Package, files []*ast.File) {
+	for _, file := range files {
+		filename := pkg.Fset.Position(file.Pos()).Filename
+		if _, ok := plan.files[filename]; !ok {
+			plan.files[filename] = typedFile{file: file, info: pkg.TypesInfo}
+		}
+	}
+}
+
+// Type helperFileKey identifies the helpers file for a package.
+type helperFileKey struct {
+	dir, pkgname string
+}
+
+func (k helperFileKey) filename() string {
+	name := helpersFile
+	if strings.HasSuffix(k.pkgname, "_test") {
+		name = helpersTestFile
+	}
+	return filepath.Join(k.dir, name)
+}
+
+func (d Downgrader) plan(s *Scanner, pkgs []*packages.Package) (*downgradePlan, error) {
+	var (
+		plan = &downgradePlan{
+			edits:   make(map[string][]analysis.TextEdit),
+			files:   make(map[string]typedFile),
+			helpers: make(map[helperFileKey]map[string]helper),
+		}
+		seen = make(map[token.Position]bool) // packages with tests share files; rewrite each position once
+	)
+
+	for _, pkg := range pkgs {
+		var files []*ast.File
+		for _, file := range pkg.Syntax {
+			filename := pkg.Fset.Position(file.Pos()).Filename
+			if isCache, err := isCacheFile(filename); err != nil || isCache {
+				continue
+			}
+			files = append(files, file)
+		}
+		if len(files) == 0 {
+			continue
+		}
+		plan.addFiles(pkg, files)
+
+		p := s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+		p.report = true
+		p.target = d.Target
+		if err := p.files(files); err != nil {
+			return nil, errors.Wrapf(err, "scanning package %s", pkg.PkgPath)
+		}
+
+		key := helperFileKey{
+			dir:     filepath.Dir(pkg.Fset.Position(files[0].Pos()).Filename),
+			pkgname: pkg.Name,
+		}
+		helperEdits := d.helperEdits(s, pkg, files, key, plan)
+
+		for _, f := range p.findings {
+			if seen[f.pos] {
+				continue
+			}
+			seen[f.pos] = true
+
+			edits := f.fix
+			if len(edits) == 0 {
+				edits = helperEdits[f.at]
+			}
+			if len(edits) == 0 {
+				plan.unrewritten = append(plan.unrewritten, f)
+				continue
+			}
+			for _, edit := range edits {
+				filename := pkg.Fset.Position(edit.Pos).Filename
+				plan.edits[filename] = append(plan.edits[filename], edit)
+			}
+		}
+	}
+
+	sort.Slice(plan.unrewritten, func(i, j int) bool {
+		return comparePositions(plan.unrewritten[i].(posResult).pos, plan.unrewritten[j].(posResult).pos) < 0
+	})
+
+	return plan, nil
+}
+
+// Method helperEdits finds references in files to stdlib identifiers above the target
+// that have helpers.
+// It returns edits replacing them,
+// indexed by position,
+// and adds the helpers to plan.
+func (d Downgrader) helperEdits(s *Scanner, pkg *packages.Package, files []*ast.File, key helperFileKey, plan *downgradePlan) map[token.Pos][]analysis.TextEdit {
+	result := make(map[token.Pos][]analysis.TextEdit)
+
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			sel, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			pkgname, ok := pkg.TypesInfo.Uses[getID(sel.X)].(*types.PkgName)
+			if !ok {
+				return true
+			}
+			pkgpath := pkgname.Imported().Path()
+			if v := s.lookup(pkgpath, sel.Sel.Name, ""); v <= d.Target {
+				return true
+			}
+			h, ok := findHelper(pkgpath, sel.Sel.Name)
+			if !ok || h.needs > d.Target {
+				return true
+			}
+			name := h.localName()
+			if obj := pkg.Types.Scope().Lookup(name); obj != nil && pkg.Fset.Position(obj.Pos()).Filename != key.filename() {
+				d.verbosef("%s: cannot use helper %s, name already in use", pkg.Fset.Position(sel.Pos()), name)
+				return true
+			}
+
+			result[sel.Pos()] = []analysis.TextEdit{{
+				Pos:     sel.Pos(),
+				End:     sel.End(),
+				NewText: []byte(name),
+			}}
+
+			helpers, ok := plan.helpers[key]
+			if !ok {
+				helpers = make(map[string]helper)
+				plan.helpers[key] = helpers
+			}
+			helpers[name] = h
+			return false
+		})
+	}
+
+	return result
+}
+
+// Method apply makes the edits in plan,
+// and writes the helpers files it needs
+// along with any generated files.
+// Imports that the edits need are added,
+// and imports that they leave unused are removed.
+//
+// Before writing anything,
+// it reloads the module in dir with the rewritten files in place of the originals,
+// and if any package then has errors,
+// it writes nothing and returns them.
+func (plan *downgradePlan) apply(fset *token.FileSet, dir string, tests bool) error {
+	sources := make(map[string][]byte)
+
+	for filename, edits := range plan.edits {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", filename)
+		}
+		src, applied, err := applyEdits(fset, src, edits)
+		if err != nil {
+			return errors.Wrapf(err, "rewriting %s", filename)
+		}
+		var remove []importRef
+		if tf, ok := plan.files[filename]; ok {
+			remove = unusedImports(fset, tf, applied)
+		}
+		src, err = fixImports(filename, src, plan.imports[filename], remove)
+		if err != nil {
+			return errors.Wrapf(err, "rewriting %s", filename)
+		}
+		sources[filename] = src
+	}
+
+	for key, helpers := range plan.helpers {
+		src, err := helpersSource(key, helpers)
+		if err != nil {
+			return errors.Wrapf(err, "generating helpers for package %s in %s", key.pkgname, key.dir)
+		}
+		sources[key.filename()] = src
+	}
+
+	for filename, src := range plan.generated {
+		sources[filename] = src
+	}
+
+	if err := checkRewrites(dir, tests, sources); err != nil {
+		return err
+	}
+
+	for _, filename := range slices.Sorted(maps.Keys(sources)) {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, sources[filename], 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", filename)
+		}
+	}
+
+	return nil
+}
+
+// Function checkRewrites loads the module in dir
+// with the given sources in place of the files on disk,
+// returning an error if any package fails to load or type-check.
+func checkRewrites(dir string, tests bool, sources map[string][]byte) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	conf := &packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedTypes,
+		Dir:     dir,
+		Tests:   tests,
+		Overlay: sources,
+	}
+	pkgs, err := packages.Load(conf, "./...")
+	if err != nil {
+		return errors.Wrap(err, "loading rewritten packages")
+	}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			err = errors.Join(err, LoadError{Err: e, Path: pkg.PkgPath})
+		}
+	}
+	return errors.Wrap(err, "rewritten code does not build, nothing written")
+}
+
+// Function applyEdits applies edits to src.
+// Where edits overlap,
+// only the first is applied
+// (and the next round of rewriting may handle the others).
+// It returns the new source and the edits that were applied.
+func applyEdits(fset *token.FileSet, src []byte, edits []analysis.TextEdit) ([]byte, []analysis.TextEdit, error) {
+	type offsetEdit struct {
+		start, end int
+		edit       analysis.TextEdit
+	}
+
+	var oedits []offsetEdit
+	for _, edit := range edits {
+		start, end := fset.Position(edit.Pos).Offset, fset.Position(edit.End).Offset
+		if start < 0 || end < start || end > len(src) {
+			return nil, nil, fmt.Errorf("invalid edit at %s", fset.Position(edit.Pos))
+		}
+		oedits = append(oedits, offsetEdit{start: start, end: end, edit: edit})
+	}
+	sort.SliceStable(oedits, func(i, j int) bool {
+		return oedits[i].start < oedits[j].start
+	})
+
+	var (
+		buf     bytes.Buffer
+		last    int
+		applied []analysis.TextEdit
+	)
+	for _, e := range oedits {
+		if e.start < last {
+			continue
+		}
+		buf.Write(src[last:e.start])
+		buf.Write(e.edit.NewText)
+		last = e.end
+		applied = append(applied, e.edit)
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes(), applied, nil
+}
+
+// Type importRef identifies an import spec by its name (if any) and path.
+type importRef struct {
+	name, path string
+}
+
+// Function unusedImports returns the imports of tf.file
+// that are used only in the text replaced by the applied edits.
+// It decides with the type information,
+// which knows each import's package name,
+// rather than guessing the name from the import path
+// (which fails for paths like example.com/lib/v2 and gopkg.in/yaml.v3).
+// An import whose name appears in the new text of an edit is kept.
+func unusedImports(fset *token.FileSet, tf typedFile, applied []analysis.TextEdit) []importRef {
+	inEdit := func(pos token.Pos) bool {
+		for _, edit := range applied {
+			if edit.Pos <= pos && pos < edit.End {
+				return true
+			}
+		}
+		return false
+	}
+
+	used := make(map[*types.PkgName]bool)
+	ast.Inspect(tf.file, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok && !inEdit(ident.Pos()) {
+			if pkgname, ok := tf.info.Uses[ident].(*types.PkgName); ok {
+				used[pkgname] = true
+			}
+		}
+		return true
+	})
+
+	newIdents := make(map[string]bool)
+	for _, edit := range applied {
+		var sc scanner.Scanner
+		file := fset.AddFile("", -1, len(edit.NewText))
+		sc.Init(file, edit.NewText, nil, 0)
+		for {
+			_, tok, lit := sc.Scan()
+			if tok == token.EOF {
+				break
+			}
+			if tok == token.IDENT {
+				newIdents[lit] = true
+			}
+		}
+	}
+
+	var result []importRef
+	for _, spec := range tf.file.Imports {
+		var obj types.Object
+		if spec.Name != nil {
+			obj = tf.info.Defs[spec.Name]
+		} else {
+			obj = tf.info.Implicits[spec]
+		}
+		pkgname, ok := obj.(*types.PkgName)
+		if !ok || pkgname.Name() == "_" || pkgname.Name() == "." {
+			continue
+		}
+		if used[pkgname] || newIdents[pkgname.Name()] {
+			continue
+		}
+		ref := importRef{path: pkgname.Imported().Path()}
+		if spec.Name != nil {
+			ref.name = spec.Name.Name
+		}
+		result = append(result, ref)
+	}
+	return result
+}
+
+// Function fixImports adds the imports in add to the file in src
+// and removes the ones in remove.
+func fixImports(filename string, src []byte, add []string, remove []importRef) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range add {
+		astutil.AddImport(fset, file, path)
+	}
+	for _, ref := range remove {
+		astutil.DeleteNamedImport(fset, file, ref.name, ref.path)
+	}
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.IMPORT && len(decl.Specs) == 1 {
+			// Print a lone import without parentheses.
+			decl.Lparen, decl.Rparen = token.NoPos, token.NoPos
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Function helpersSource produces the helpers file for a package,
+// including any helpers already in it.
+func helpersSource(key helperFileKey, helpers map[string]helper) ([]byte, error) {
+	filename := key.filename()
+
+	if src, err := os.ReadFile(filename); err == nil {
+		file, err := parser.ParseFile(token.NewFileSet(), filename, src, 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", filename)
+		}
+		for _, h := range helperTable {
+			if declares(file, h.localName()) {
+				helpers[h.localName()] = h
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var (
+		names   = slices.Sorted(maps.Keys(helpers))
+		imports = make(map[string]bool)
+	)
+	for _, h := range helpers {
+		for _, imp := range h.imports {
+			imports[imp] = true
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintln(buf, "// Code generated by mingo downgrade. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "package %s\n", key.pkgname)
+	writeImports(buf, slices.Sorted(maps.Keys(imports)))
+	for _, name := range names {
+		fmt.Fprintln(buf)
+		fmt.Fprintf(buf, helpers[name].code, name)
+		fmt.Fprintln(buf)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, errors.Wrapf(err, "formatting %s", filename)
+	}
+	return src, nil
+}
+
+func writeImports(w io.Writer, imports []string) {
+	switch len(imports) {
+	case 0:
+	case 1:
+		fmt.Fprintf(w, "\nimport %q\n", imports[0])
+	default:
+		fmt.Fprintln(w, "\nimport (")
+		for _, imp := range imports {
+			fmt.Fprintf(w, "\t%q\n", imp)
+		}
+		fmt.Fprintln(w, ")")
+	}
+}
+
+// Function declares tells whether file has a top-level declaration of name.
+func declares(file *ast.File, name string) bool {
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name == name {
+				return true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					for _, id := range spec.Names {
+						if id.Name == name {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Method lowerGoMod lowers the go directive in dir/go.mod to the target,
+// if it is higher.
+func (d Downgrader) lowerGoMod(dir string) error {
+	gomodPath := filepath.Join(dir, "go.mod")
+	gomodBytes, err := os.ReadFile(gomodPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", gomodPath)
+	}
+	f, err := modfile.Parse(gomodPath, gomodBytes, nil)
+	if err != nil {
+		return errors.Wrapf(err, "parsing %s", gomodPath)
+	}
+	if f.Go == nil {
+		return nil
+	}
+	declared, err := parseGoVersion(f.Go.Version)
+	if err != nil {
+		return errors.Wrapf(err, "in %s", gomodPath)
+	}
+	if declared <= d.Target {
+		return nil
+	}
+	if err := f.AddGoStmt(fmt.Sprintf("1.%d", d.Target)); err != nil {
+		return errors.Wrapf(err, "updating go directive in %s", gomodPath)
+	}
+	if d.Target < 21 && f.Toolchain != nil {
+		// The toolchain directive is not understood before Go 1.21.
+		f.DropToolchainStmt()
+	}
+	out, err := f.Format()
+	if err != nil {
+		return errors.Wrapf(err, "formatting %s", gomodPath)
+	}
+	d.verbosef("lowering go directive in %s from 1.%d to 1.%d", gomodPath, declared, d.Target)
+	return os.WriteFile(gomodPath, out, 0644)
+}
+
+func (d Downgrader) verbosef(format string, args ...any) {
+	if !d.Verbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -0,0 +1,142 @@
+package mingo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestDowngrade(t *testing.T) {
+	cases := []struct {
+		target, want    int
+		wantUnrewritten []string
+		wantGo          string
+		wantHelpers     []string
+	}{{
+		target:          19,
+		want:            20,
+		wantUnrewritten: []string{`"errors".Join`},
+		wantGo:          "go 1.22",
+		wantHelpers:     []string{"slicesContains", "stringsCutPrefix"},
+	}, {
+		target:      20,
+		want:        20,
+		wantGo:      "go 1.20",
+		wantHelpers: []string{"slicesContains"},
+	}}
+
+	for _, tc := range cases {
+		t.Run(strconv.Itoa(tc.target), func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.CopyFS(dir, os.DirFS("testdata/downgrade")); err != nil {
+				t.Fatal(err)
+			}
+
+			d := Downgrader{Target: tc.target}
+			result, unrewritten, err := d.Run(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Version() != tc.want {
+				t.Errorf("got version %d, want %d [%s]", result.Version(), tc.want, result)
+			}
+
+			var got []string
+			for _, r := range unrewritten {
+				got = append(got, r.(posResult).desc)
+			}
+			if !slices.Equal(got, tc.wantUnrewritten) {
+				t.Errorf("got unrewritten %v, want %v", got, tc.wantUnrewritten)
+			}
+
+			gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(gomod), tc.wantGo+"\n") {
+				t.Errorf("go.mod does not contain %q:\n%s", tc.wantGo, gomod)
+			}
+
+			helpers, err := os.ReadFile(filepath.Join(dir, helpersFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tc.wantHelpers {
+				if !strings.Contains(string(helpers), "func "+name+"[") && !strings.Contains(string(helpers), "func "+name+"(") {
+					t.Errorf("helpers file does not define %s:\n%s", name, helpers)
+				}
+			}
+
+			// The rewritten module must still build.
+			conf := &packages.Config{Mode: packages.NeedTypes, Dir: dir}
+			pkgs, err := packages.Load(conf, "./...")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, pkg := range pkgs {
+				for _, e := range pkg.Errors {
+					t.Error(e)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRewrites(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/downgrade")); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "downgrade.go")
+
+	if err := checkRewrites(dir, false, map[string][]byte{filename: []byte("package downgrade\n\nfunc F() int { return lib.F() }\n")}); err == nil {
+		t.Error("got no error for a rewrite that does not build")
+	}
+	if err := checkRewrites(dir, false, map[string][]byte{filename: []byte("package downgrade\n\nfunc F() int { return 1 }\n")}); err != nil {
+		t.Errorf("got error %v for a rewrite that builds", err)
+	}
+}
+
+func TestDowngradeNumericLiterals(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/lits\n\ngo 1.13\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "lits.go")
+	if err := os.WriteFile(filename, []byte("package lits\n\nvar X = []int{0b1010, 0o17, 1_000, 0xff}\n\nvar Y = 0b11i\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := Downgrader{Target: 12}
+	result, unrewritten, err := d.Run(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version() > 12 {
+		t.Errorf("got version %d, want at most 12 [%s]", result.Version(), result)
+	}
+	if len(unrewritten) > 0 {
+		t.Errorf("got unrewritten %v, want none", unrewritten)
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "var X = []int{0xa, 017, 1000, 0xff}\n\nvar Y = 3i\n"; !strings.HasSuffix(string(src), want) {
+		t.Errorf("got:\n%s\nwant it to end with:\n%s", src, want)
+	}
+
+	gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(gomod), "go 1.12\n") {
+		t.Errorf("go.mod does not contain go 1.12:\n%s", gomod)
+	}
+}
@@ -1,6 +1,7 @@
 package mingo
 
 import (
+	"context"
 	"io/fs"
 	"testing"
 
@@ -13,20 +14,20 @@ func TestScanDepsErrs(t *testing.T) {
 	}
 
 	t.Run("NotExist", func(t *testing.T) {
-		err := s.scanDeps("IDONOTEXIST")
+		err := s.newScanState().scanDeps(context.Background(), "IDONOTEXIST")
 		if !errors.Is(err, fs.ErrNotExist) {
 			t.Errorf("got error %v, want fs.ErrNotExist", err)
 		}
 	})
 
 	t.Run("BadGoMod", func(t *testing.T) {
-		if err := s.scanDeps("testdata/go.mod.bad"); err == nil {
+		if err := s.newScanState().scanDeps(context.Background(), "testdata/go.mod.bad"); err == nil {
 			t.Error("expected error")
 		}
 	})
 
 	t.Run("BadDepScan", func(t *testing.T) {
-		err := s.scanDeps("testdata/go.mod")
+		err := s.newScanState().scanDeps(context.Background(), "testdata/go.mod")
 		if !errors.Is(err, depScannerErr) {
 			t.Errorf("got error %v, want %v", err, depScannerErr)
 		}
@@ -37,6 +38,6 @@ type errDepScanner struct{}
 
 var depScannerErr = errors.New("scan failed")
 
-func (errDepScanner) scan(modpath, version string) (modDownload, error) {
+func (errDepScanner) scan(_ context.Context, modpath, version string) (modDownload, error) {
 	return modDownload{}, depScannerErr
 }
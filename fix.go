@@ -0,0 +1,314 @@
+package mingo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math/big"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// These are the Fix functions for some of the rules in lang.go.
+// Each one rewrites a single node,
+// and only when the rewrite means exactly the same thing.
+
+func replace(node ast.Node, text string) []analysis.TextEdit {
+	return []analysis.TextEdit{{
+		Pos:     node.Pos(),
+		End:     node.End(),
+		NewText: []byte(text),
+	}}
+}
+
+func fixExpandedNumericLiteral(_ *RuleContext, node ast.Node) []analysis.TextEdit {
+	lit, ok := node.(*ast.BasicLit)
+	if !ok {
+		return nil
+	}
+	text, ok := oldNumericLiteral(lit.Kind, lit.Value)
+	if !ok || text == lit.Value {
+		return nil
+	}
+	return replace(lit, text)
+}
+
+// Function oldNumericLiteral rewrites a numeric literal in pre-Go-1.13 syntax:
+// no underscores,
+// no 0b or 0o prefixes,
+// and only decimal integer parts in imaginary literals.
+// Hexadecimal floating-point literals have no such equivalent.
+func oldNumericLiteral(kind token.Token, lit string) (string, bool) {
+	lit = strings.ReplaceAll(lit, "_", "")
+
+	var suffix string
+	if kind == token.IMAG {
+		lit = strings.TrimSuffix(lit, "i")
+		suffix = "i"
+	}
+
+	var prefix string
+	if len(lit) > 1 && lit[0] == '0' {
+		prefix = strings.ToLower(lit[:2])
+	}
+
+	switch prefix {
+	case "0x":
+		if strings.ContainsAny(lit, ".pP") {
+			return "", false
+		}
+	case "0b", "0o":
+	default:
+		// Decimal (or old-style octal).
+		return lit + suffix, true
+	}
+
+	n, ok := new(big.Int).SetString(lit, 0)
+	if !ok {
+		return "", false
+	}
+
+	switch {
+	case kind == token.IMAG:
+		return n.String() + suffix, true
+	case prefix == "0b":
+		return "0x" + n.Text(16), true
+	case prefix == "0o":
+		return "0" + n.Text(8), true
+	}
+	return lit, true
+}
+
+func fixAny(_ *RuleContext, node ast.Node) []analysis.TextEdit {
+	return replace(node, "interface{}")
+}
+
+// Function fixMinMax replaces a call to the min or max builtin
+// with its constant value,
+// or with a call to an equivalent function literal.
+// Floating-point calls are left alone,
+// since the builtins' treatment of NaNs and negative zero is not simple to reproduce.
+func fixMinMax(c *RuleContext, node ast.Node) []analysis.TextEdit {
+	expr, name := builtinCall(c, node)
+	if expr == nil || len(expr.Args) == 0 {
+		return nil
+	}
+	tv, ok := c.Info.Types[expr]
+	if !ok {
+		return nil
+	}
+	basic, ok := tv.Type.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsFloat != 0 || basic.Kind() == types.UntypedRune {
+		return nil
+	}
+
+	if tv.Value != nil {
+		text := tv.Value.ExactString()
+		if basic.Info()&types.IsUntyped == 0 {
+			typestr, ok := c.typeString(tv.Type)
+			if !ok {
+				return nil
+			}
+			text = fmt.Sprintf("%s(%s)", typestr, text)
+		}
+		return replace(expr, text)
+	}
+
+	if len(expr.Args) == 1 {
+		if _, ok := expr.Args[0].(*ast.Ident); ok {
+			return replace(expr, c.source(expr.Args[0]))
+		}
+		return replace(expr, "("+c.source(expr.Args[0])+")")
+	}
+
+	typestr, ok := c.typeString(tv.Type)
+	if !ok {
+		return nil
+	}
+	op := "<"
+	if name == "max" {
+		op = ">"
+	}
+	var args []string
+	for _, arg := range expr.Args {
+		args = append(args, c.source(arg))
+	}
+	text := fmt.Sprintf("func(x %[1]s, ys ...%[1]s) %[1]s { for _, y := range ys { if y %[2]s x { x = y } }; return x }(%[3]s)", typestr, op, strings.Join(args, ", "))
+	return replace(expr, text)
+}
+
+// Function fixRangeOverInt rewrites "for i := range n" as a three-clause loop.
+// It does so only when the body leaves i alone,
+// and does not capture i in a closure or take its address
+// (where it would behave differently under the pre-Go-1.22 loop-variable semantics).
+//
+// The range expression is evaluated once,
+// but the loop condition every time around,
+// so "i < n" serves only when n is a constant,
+// or a local variable that the body leaves alone
+// and that nothing else can change
+// (no closure captures it and its address is never taken).
+// Otherwise the rewrite copies n first: "for i, n0 := 0, n; i < n0; i++".
+func fixRangeOverInt(c *RuleContext, node ast.Node) []analysis.TextEdit {
+	stmt, ok := node.(*ast.RangeStmt)
+	if !ok || stmt.Tok != token.DEFINE || stmt.Value != nil {
+		return nil
+	}
+	key, ok := stmt.Key.(*ast.Ident)
+	if !ok || key.Name == "_" {
+		return nil
+	}
+	keyObj := c.Info.Defs[key]
+	if keyObj == nil || !unmodified(c.Info, stmt.Body, keyObj, false) {
+		return nil
+	}
+
+	init := "0"
+	if !types.Identical(keyObj.Type(), types.Typ[types.Int]) {
+		typestr, ok := c.typeString(keyObj.Type())
+		if !ok {
+			return nil
+		}
+		init = typestr + "(0)"
+	}
+
+	var text string
+	if c.Info.Types[stmt.X].Value != nil || c.stableLocal(stmt) {
+		text = fmt.Sprintf("%[1]s := %[2]s; %[1]s < %[3]s; %[1]s++", key.Name, init, c.source(stmt.X))
+	} else {
+		bound := unusedName(stmt.Body, key.Name, "n")
+		text = fmt.Sprintf("%[1]s, %[4]s := %[2]s, %[3]s; %[1]s < %[4]s; %[1]s++", key.Name, init, c.source(stmt.X), bound)
+	}
+	return []analysis.TextEdit{{
+		Pos:     key.Pos(),
+		End:     stmt.X.End(),
+		NewText: []byte(text),
+	}}
+}
+
+// Method stableLocal tells whether the range expression of stmt
+// is a local variable that only the enclosing function can change,
+// and that the loop body leaves alone.
+func (c *RuleContext) stableLocal(stmt *ast.RangeStmt) bool {
+	x, ok := ast.Unparen(stmt.X).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	xObj, ok := c.Info.Uses[x].(*types.Var)
+	if !ok || !unmodified(c.Info, stmt.Body, xObj, true) {
+		return false
+	}
+	for _, node := range c.stack {
+		if decl, ok := node.(*ast.FuncDecl); ok {
+			return decl.Pos() <= xObj.Pos() && xObj.Pos() < decl.End() && !captured(c.Info, decl, xObj)
+		}
+	}
+	return false
+}
+
+// Function captured tells whether node takes the address of the variable obj
+// (explicitly, or by calling a pointer method on it)
+// or refers to it in a function literal,
+// either of which lets other code change it.
+func captured(info *types.Info, node ast.Node, obj types.Object) bool {
+	isObj := func(expr ast.Expr) bool {
+		id, ok := ast.Unparen(expr).(*ast.Ident)
+		return ok && info.Uses[id] == obj
+	}
+
+	var result bool
+	ast.Inspect(node, func(node ast.Node) bool {
+		if result {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.UnaryExpr:
+			if node.Op == token.AND && isObj(node.X) {
+				result = true
+			}
+		case *ast.SelectorExpr:
+			if sel := info.Selections[node]; sel != nil && sel.Kind() != types.FieldVal && isObj(node.X) {
+				if _, ok := sel.Obj().Type().(*types.Signature).Recv().Type().(*types.Pointer); ok {
+					result = true
+				}
+			}
+		case *ast.FuncLit:
+			ast.Inspect(node.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && info.Uses[id] == obj {
+					result = true
+				}
+				return !result
+			})
+		}
+		return !result
+	})
+	return result
+}
+
+// Function unusedName returns a name, base0 or base1 etc.,
+// that is neither taken nor referred to in body,
+// so that declaring it around body shadows nothing.
+func unusedName(body *ast.BlockStmt, taken, base string) string {
+	names := map[string]bool{taken: true}
+	ast.Inspect(body, func(node ast.Node) bool {
+		if id, ok := node.(*ast.Ident); ok {
+			names[id.Name] = true
+		}
+		return true
+	})
+	for i := 0; ; i++ {
+		if name := fmt.Sprintf("%s%d", base, i); !names[name] {
+			return name
+		}
+	}
+}
+
+// Function unmodified tells whether body leaves the variable obj alone:
+// it does not assign to it or take its address,
+// and (unless closures is true) does not refer to it in a function literal.
+func unmodified(info *types.Info, body *ast.BlockStmt, obj types.Object, closures bool) bool {
+	isObj := func(expr ast.Expr) bool {
+		id, ok := ast.Unparen(expr).(*ast.Ident)
+		return ok && info.Uses[id] == obj
+	}
+
+	result := true
+	ast.Inspect(body, func(node ast.Node) bool {
+		if !result {
+			return false
+		}
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range node.Lhs {
+				if isObj(lhs) {
+					result = false
+				}
+			}
+		case *ast.IncDecStmt:
+			if isObj(node.X) {
+				result = false
+			}
+		case *ast.RangeStmt:
+			if node.Tok == token.ASSIGN && (isObj(node.Key) || isObj(node.Value)) {
+				result = false
+			}
+		case *ast.UnaryExpr:
+			if node.Op == token.AND && isObj(node.X) {
+				result = false
+			}
+		case *ast.FuncLit:
+			if !closures {
+				ast.Inspect(node.Body, func(n ast.Node) bool {
+					if id, ok := n.(*ast.Ident); ok && info.Uses[id] == obj {
+						result = false
+					}
+					return result
+				})
+			}
+		}
+		return result
+	})
+	return result
+}
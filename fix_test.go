@@ -0,0 +1,36 @@
+package mingo
+
+import (
+	"go/token"
+	"testing"
+)
+
+func TestOldNumericLiteral(t *testing.T) {
+	cases := []struct {
+		kind     token.Token
+		lit, out string
+		ok       bool
+	}{
+		{kind: token.INT, lit: "1_000", out: "1000", ok: true},
+		{kind: token.INT, lit: "0B101", out: "0x5", ok: true},
+		{kind: token.INT, lit: "0o0", out: "00", ok: true},
+		{kind: token.INT, lit: "0XFF", out: "0XFF", ok: true},
+		{kind: token.INT, lit: "017", out: "017", ok: true},
+		{kind: token.FLOAT, lit: "1_0.5e1_0", out: "10.5e10", ok: true},
+		{kind: token.FLOAT, lit: "0x1p-2", ok: false},
+		{kind: token.IMAG, lit: "0x10i", out: "16i", ok: true},
+		{kind: token.IMAG, lit: "017i", out: "017i", ok: true},
+		{kind: token.IMAG, lit: "1_0.5i", out: "10.5i", ok: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lit, func(t *testing.T) {
+			out, ok := oldNumericLiteral(tc.kind, tc.lit)
+			if ok != tc.ok {
+				t.Fatalf("got ok %v, want %v", ok, tc.ok)
+			}
+			if ok && out != tc.out {
+				t.Errorf("got %s, want %s", out, tc.out)
+			}
+		})
+	}
+}
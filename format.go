@@ -0,0 +1,307 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode/utf8"
+)
+
+// These are the Match functions for the rules about
+// the format strings of fmt.Printf and friends
+// and the layouts of time.Parse and time.Time.Format.
+// What these strings mean has changed over time,
+// but any string compiles,
+// so the rules are [Rule.Semantic].
+
+// Functions and methods taking a format string,
+// mapped to the index of that argument.
+// Methods are keyed by package, receiver type, and name.
+var formatFuncs = map[string]int{
+	"fmt.Appendf":       1,
+	"fmt.Errorf":        0,
+	"fmt.Fprintf":       1,
+	"fmt.Printf":        0,
+	"fmt.Sprintf":       0,
+	"log.Fatalf":        0,
+	"log.Panicf":        0,
+	"log.Printf":        0,
+	"log.Logger.Fatalf": 0,
+	"log.Logger.Panicf": 0,
+	"log.Logger.Printf": 0,
+
+	// The methods of testing.T, testing.B, and testing.F come from the unexported testing.common.
+	"testing.common.Errorf": 0,
+	"testing.common.Fatalf": 0,
+	"testing.common.Logf":   0,
+	"testing.common.Skipf":  0,
+	"testing.TB.Errorf":     0,
+	"testing.TB.Fatalf":     0,
+	"testing.TB.Logf":       0,
+	"testing.TB.Skipf":      0,
+}
+
+// Functions and methods taking a time layout,
+// mapped to the index of that argument.
+var layoutFuncs = map[string]int{
+	"time.Parse":             0,
+	"time.ParseInLocation":   0,
+	"time.Time.AppendFormat": 1,
+	"time.Time.Format":       0,
+}
+
+// Go 1.13 added the %w verb of fmt.Errorf.
+// Before that, it formats as %!w(...) and wraps nothing.
+func matchErrorfWrap(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	return matchErrorfWraps(c, node, 1)
+}
+
+// Go 1.20 allows more than one %w verb in fmt.Errorf.
+// Before that, the second one formats as %!w(...).
+func matchErrorfMultipleWrap(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	return matchErrorfWraps(c, node, 2)
+}
+
+func matchErrorfWraps(c *RuleContext, node ast.Node, n int) (token.Pos, bool) {
+	call, name, format, ok := formatCall(c, node)
+	if !ok || name != "fmt.Errorf" {
+		return token.NoPos, false
+	}
+	var count int
+	for _, v := range parseFormat(format) {
+		if v.verb == 'w' {
+			count++
+		}
+	}
+	if count < n {
+		return token.NoPos, false
+	}
+	return call.Args[formatFuncs[name]].Pos(), true
+}
+
+// Go 1.13 added the %O verb,
+// for octal with a 0o prefix.
+func matchFormatOctalO(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	call, name, format, ok := formatCall(c, node)
+	if !ok {
+		return token.NoPos, false
+	}
+	for _, v := range parseFormat(format) {
+		if v.verb == 'O' {
+			return call.Args[formatFuncs[name]].Pos(), true
+		}
+	}
+	return token.NoPos, false
+}
+
+// Go 1.13 added hexadecimal formatting of floating-point and complex numbers
+// with %x and %X.
+// The operand must be an explicit argument to tell.
+func matchFormatHexFloat(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	call, name, format, ok := formatCall(c, node)
+	if !ok || call.Ellipsis.IsValid() {
+		return token.NoPos, false
+	}
+	first := formatFuncs[name] + 1
+	for _, v := range parseFormat(format) {
+		if (v.verb != 'x' && v.verb != 'X') || v.arg < 0 || first+v.arg >= len(call.Args) {
+			continue
+		}
+		tv, ok := c.Info.Types[call.Args[first+v.arg]]
+		if !ok {
+			continue
+		}
+		if basic, ok := tv.Type.Underlying().(*types.Basic); ok && basic.Info()&(types.IsFloat|types.IsComplex) != 0 {
+			return call.Args[formatFuncs[name]].Pos(), true
+		}
+	}
+	return token.NoPos, false
+}
+
+// Go 1.17 accepts a comma,
+// as well as a period,
+// before the fractional seconds in a time layout,
+// as in "15:04:05,000".
+// Earlier versions take it literally.
+func matchTimeLayoutComma(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return token.NoPos, false
+	}
+	name, ok := calleeName(c, call.Fun)
+	if !ok {
+		return token.NoPos, false
+	}
+	idx, ok := layoutFuncs[name]
+	if !ok || idx >= len(call.Args) {
+		return token.NoPos, false
+	}
+	layout, ok := constantString(c, call.Args[idx])
+	if !ok || !hasCommaFraction(layout) {
+		return token.NoPos, false
+	}
+	return call.Args[idx].Pos(), true
+}
+
+// Function hasCommaFraction tells whether layout has fractional seconds after a comma,
+// as recognized by the time package:
+// a run of 0s or 9s not followed by another digit.
+func hasCommaFraction(layout string) bool {
+	for i := 0; i < len(layout)-1; i++ {
+		if layout[i] != ',' || (layout[i+1] != '0' && layout[i+1] != '9') {
+			continue
+		}
+		ch := layout[i+1]
+		j := i + 1
+		for j < len(layout) && layout[j] == ch {
+			j++
+		}
+		if j == len(layout) || layout[j] < '0' || layout[j] > '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// Function formatCall tells whether node is a call to one of the [formatFuncs]
+// with a constant format string,
+// and if so returns the call, the key of the function in formatFuncs, and the format string.
+func formatCall(c *RuleContext, node ast.Node) (*ast.CallExpr, string, string, bool) {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return nil, "", "", false
+	}
+	name, ok := calleeName(c, call.Fun)
+	if !ok {
+		return nil, "", "", false
+	}
+	idx, ok := formatFuncs[name]
+	if !ok || idx >= len(call.Args) {
+		return nil, "", "", false
+	}
+	format, ok := constantString(c, call.Args[idx])
+	if !ok {
+		return nil, "", "", false
+	}
+	return call, name, format, true
+}
+
+// Function calleeName returns the name of the stdlib function or method called by fun
+// in the form of the keys of [formatFuncs].
+func calleeName(c *RuleContext, fun ast.Expr) (string, bool) {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return "", false
+	}
+	fn, ok := c.Info.Uses[ident].(*types.Func)
+	if !ok || fn.Pkg() == nil || !isStdlib(fn.Pkg().Path()) {
+		return "", false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return fn.Pkg().Path() + "." + fn.Name(), true
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return "", false
+	}
+	return fn.Pkg().Path() + "." + named.Obj().Name() + "." + fn.Name(), true
+}
+
+func constantString(c *RuleContext, expr ast.Expr) (string, bool) {
+	tv, ok := c.Info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// Type formatVerb is a verb in a format string.
+type formatVerb struct {
+	verb rune
+	arg  int // the index of its operand among the arguments after the format string, or -1 for %%
+}
+
+// Function parseFormat returns the verbs in a format string,
+// following the rules of the fmt package for flags, widths, precisions, and explicit argument indexes.
+func parseFormat(format string) []formatVerb {
+	var (
+		result []formatVerb
+		arg    int
+	)
+
+	// Function argIndex consumes an explicit argument index like [2] at format[i:].
+	argIndex := func(i int) int {
+		if i >= len(format) || format[i] != '[' {
+			return i
+		}
+		end := strings.IndexByte(format[i:], ']')
+		if end < 0 {
+			return i
+		}
+		n := 0
+		for _, ch := range format[i+1 : i+end] {
+			if ch < '0' || ch > '9' {
+				return i + end + 1
+			}
+			n = 10*n + int(ch-'0')
+		}
+		if n > 0 {
+			arg = n - 1
+		}
+		return i + end + 1
+	}
+
+	// Function number consumes a width or precision at format[i:].
+	number := func(i int) int {
+		i = argIndex(i)
+		if i < len(format) && format[i] == '*' {
+			arg++
+			return i + 1
+		}
+		for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		i = number(i)
+		if i < len(format) && format[i] == '.' {
+			i = number(i + 1)
+		}
+		i = argIndex(i)
+		if i >= len(format) {
+			break
+		}
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		i += size
+		if verb == '%' {
+			result = append(result, formatVerb{verb: verb, arg: -1})
+			continue
+		}
+		result = append(result, formatVerb{verb: verb, arg: arg})
+		arg++
+	}
+
+	return result
+}
@@ -0,0 +1,102 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	cases := []struct {
+		format string
+		want   []formatVerb
+	}{
+		{"", nil},
+		{"hello", nil},
+		{"%d%%%s", []formatVerb{{'d', 0}, {'%', -1}, {'s', 1}}},
+		{"%+-#08.3f %x", []formatVerb{{'f', 0}, {'x', 1}}},
+		{"%*.*x %v", []formatVerb{{'x', 2}, {'v', 3}}},
+		{"%[2]d %[1]x %v", []formatVerb{{'d', 1}, {'x', 0}, {'v', 1}}},
+		{"%[3]*.[2]*[1]f", []formatVerb{{'f', 0}}},
+		{"%w: %w", []formatVerb{{'w', 0}, {'w', 1}}},
+		{"trailing %", nil},
+		{"%é", []formatVerb{{'é', 0}}},
+	}
+	for _, tc := range cases {
+		if got := parseFormat(tc.format); !slices.Equal(got, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestFormatRules(t *testing.T) {
+	cases := []struct {
+		name, stmt string
+		want       []string
+	}{
+		{"wrap", `_ = fmt.Errorf("doing x: %w", err)`, []string{"fmt-errorf-wrap"}},
+		{"multiple-wrap", `_ = fmt.Errorf("%w and %w", err, err)`, []string{"fmt-errorf-wrap", "fmt-errorf-multiple-wrap"}},
+		{"wrap-printf", `fmt.Printf("%w", err)`, nil},
+		{"wrap-variable", `_ = fmt.Errorf(format, err)`, nil},
+		{"octal", `log.Printf("%O", 8)`, []string{"fmt-octal-prefix"}},
+		{"hex-float", `t.Logf("%d %x", 1, 2.5)`, []string{"fmt-hex-float"}},
+		{"hex-float-index", `_ = fmt.Sprintf("%[2]x %[1]d", 1, f)`, []string{"fmt-hex-float"}},
+		{"hex-int", `_ = fmt.Sprintf("%x", 255)`, nil},
+		{"hex-ellipsis", `_ = fmt.Sprintf("%x", args...)`, nil},
+		{"logger", `logger.Fatalf("%O", 8)`, []string{"fmt-octal-prefix"}},
+		{"tb", `tb.Errorf("%O", 8)`, []string{"fmt-octal-prefix"}},
+		{"layout-comma", `_, _ = time.Parse("15:04:05,000", "")`, []string{"time-layout-comma"}},
+		{"layout-comma-nines", `_ = now.Format(time.DateOnly + " 15:04:05,999999")`, []string{"time-layout-comma"}},
+		{"layout-append", `_ = now.AppendFormat(nil, "05,0")`, []string{"time-layout-comma"}},
+		{"layout-period", `_ = now.Format("15:04:05.000")`, nil},
+		{"layout-comma-number", `_ = now.Format("Jan 2, 2006")`, nil},
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	rules := []Rule{}
+	for _, r := range langRules {
+		switch r.ID {
+		case "fmt-errorf-wrap", "fmt-errorf-multiple-wrap", "fmt-hex-float", "fmt-octal-prefix", "time-layout-comma":
+			rules = append(rules, r)
+		}
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := "package p\n\nimport (\n\t\"fmt\"\n\t\"log\"\n\t\"testing\"\n\t\"time\"\n)\n\nvar _, _ = fmt.Sprint, log.Print\n\nfunc f(t *testing.T, tb testing.TB, logger *log.Logger, now time.Time, err error, f float64, format string, args []any) {\n\t" + tc.stmt + "\n}\n"
+
+			file, err := parser.ParseFile(fset, tc.name+".go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Types: make(map[ast.Expr]types.TypeAndValue),
+				Uses:  make(map[*ast.Ident]types.Object),
+			}
+			conf := &types.Config{Importer: imp}
+			if _, err := conf.Check("p", fset, []*ast.File{file}, info); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &RuleContext{Fset: fset, Info: info, file: file}
+			var got []string
+			ast.Inspect(file, func(node ast.Node) bool {
+				for _, r := range rules {
+					if _, ok := r.Match(c, node); ok {
+						got = append(got, r.ID)
+					}
+				}
+				return true
+			})
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package mingo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// GatePlanner plans how to split the packages in a module into build-constrained files
+// so that the module builds with Go 1.Target,
+// while newer toolchains still get the code that needs them.
+//
+// Each top-level declaration that uses a feature above the target
+// must move to a file constrained by //go:build go1.N.
+// Where other code refers to such a declaration
+// (or it is exported),
+// a fallback stub is needed in a file constrained by //go:build !go1.N.
+// A declaration whose signature uses a newer feature cannot be stubbed,
+// so the declarations that refer to it must move too.
+type GatePlanner struct {
+	Target  int    // The minor version of Go 1.x that the module should build with.
+	HistDir string // Find Go stdlib history in this directory (default: $GOROOT/api).
+	Tests   bool   // Include *_test.go files.
+	Verbose bool   // Be verbose.
+}
+
+// GatePlan is the result of [GatePlanner.Run].
+type GatePlan struct {
+	Moves []GateMove
+
+	// Result is the module's minimum Go version after the moves,
+	// not counting dependencies.
+	Result Result
+}
+
+// GateMove is a declaration that must move to a file with a //go:build go1.Version constraint.
+type GateMove struct {
+	PkgPath string
+	Name    string         // The name of the declaration, e.g. "F", "T", or "(*T).M".
+	Pos     token.Position // The position of the declaration.
+	Version int            // The minor version of Go 1.x needed by the declaration.
+	Reason  string         // Why the declaration must move.
+
+	// Stub is a fallback declaration for the //go:build !go1.Version file,
+	// if the package needs one.
+	Stub string
+
+	// Missing means that the declaration is exported but cannot be stubbed,
+	// so that it will be absent from the package before Go 1.Version.
+	Missing bool
+}
+
+func (m GateMove) String() string {
+	return fmt.Sprintf("%s: %s needs go1.%d: %s", m.Pos, m.Name, m.Version, m.Reason)
+}
+
+// Run computes a plan for the module in dir.
+func (g GatePlanner) Run(dir string) (*GatePlan, error) {
+	s := &Scanner{
+		HistDir: g.HistDir,
+		Tests:   g.Tests,
+		Verbose: g.Verbose,
+	}
+	if err := s.ensureHistory(); err != nil {
+		return nil, err
+	}
+
+	d := Downgrader{Target: g.Target, Tests: g.Tests}
+	pkgs, err := d.load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		plan = &GatePlan{Result: intResult(0)}
+		seen = make(map[token.Position]bool) // packages with tests share files
+	)
+	for _, pkg := range pkgs {
+		moves, result, err := g.planPackage(s, pkg, seen)
+		if err != nil {
+			return nil, errors.Wrapf(err, "planning package %s", pkg.PkgPath)
+		}
+		plan.Moves = append(plan.Moves, moves...)
+		if result.Version() > plan.Result.Version() {
+			plan.Result = result
+		}
+	}
+
+	slices.SortFunc(plan.Moves, func(a, b GateMove) int {
+		return comparePositions(a.Pos, b.Pos)
+	})
+
+	return plan, nil
+}
+
+// Type gateDecl is a top-level declaration:
+// a function, method, type spec, or var or const spec.
+// Types and consts cannot have stubs,
+// since there is no way to write one without the newer feature.
+type gateDecl struct {
+	node ast.Node
+	sig  []ast.Node // the parts of node that a stub must repeat
+	name string
+	pos  token.Position
+
+	exported bool
+	uses     map[*gateDecl]bool // declarations in the package that this one refers to
+	sigUses  map[*gateDecl]bool // the subset of uses in sig
+	usedBy   map[*gateDecl]bool
+
+	version    int       // the version needed by the body, or 0
+	sigVersion int       // the version needed by the signature, or 0
+	reason     posResult // the finding that sets version
+
+	canStub   bool // whether this kind of declaration can have a stub at all
+	stubbable bool // whether this declaration can have a stub, given the plan so far
+	move      bool
+	forcedBy  *gateDecl // when move is due to a dependency
+}
+
+func (g GatePlanner) planPackage(s *Scanner, pkg *packages.Package, seen map[token.Position]bool) ([]GateMove, Result, error) {
+	var files []*ast.File
+	for _, file := range pkg.Syntax {
+		filename := pkg.Fset.Position(file.Pos()).Filename
+		if isCache, err := isCacheFile(filename); err != nil || isCache {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	// Collect every finding, not just those above the target,
+	// to compute the result of the plan.
+	p := s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+	p.report = true
+	if err := p.files(files); err != nil {
+		return nil, nil, err
+	}
+
+	decls, byObj := gateDecls(pkg, files)
+
+	var result Result = intResult(0)
+	for _, f := range p.findings {
+		decl := containing(decls, f.at)
+		if decl == nil || f.version <= g.Target {
+			if f.version > result.Version() {
+				result = f
+			}
+			continue
+		}
+		inSig := false
+		for _, n := range decl.sig {
+			if n.Pos() <= f.at && f.at < n.End() {
+				inSig = true
+				break
+			}
+		}
+		if inSig {
+			decl.sigVersion = max(decl.sigVersion, f.version)
+		}
+		if f.version > decl.version {
+			decl.version = f.version
+			decl.reason = f
+		}
+	}
+
+	for _, decl := range decls {
+		decl.uses = gateUses(pkg.TypesInfo, byObj, decl, []ast.Node{decl.node})
+		decl.sigUses = gateUses(pkg.TypesInfo, byObj, decl, decl.sig)
+		for dep := range decl.uses {
+			dep.usedBy[decl] = true
+		}
+	}
+
+	// Move the declarations with findings above the target,
+	// plus the ones that refer to unstubbable moved declarations,
+	// until nothing changes.
+	for _, decl := range decls {
+		decl.move = decl.version > g.Target
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, decl := range decls {
+			decl.stubbable = decl.canStub && decl.sigVersion <= g.Target
+			for dep := range decl.sigUses {
+				if dep.move && !dep.stubbable {
+					decl.stubbable = false
+				}
+			}
+		}
+		for _, decl := range decls {
+			if !decl.move || decl.stubbable {
+				continue
+			}
+			for user := range decl.usedBy {
+				if !user.move {
+					user.move, user.forcedBy, changed = true, decl, true
+				}
+				if decl.version > user.version {
+					user.version = decl.version
+					user.forcedBy, changed = decl, true
+				}
+			}
+		}
+	}
+
+	var moves []GateMove
+	for _, decl := range decls {
+		if !decl.move {
+			continue
+		}
+		if seen[decl.pos] {
+			continue
+		}
+		seen[decl.pos] = true
+
+		m := GateMove{
+			PkgPath: pkg.PkgPath,
+			Name:    decl.name,
+			Pos:     decl.pos,
+			Version: decl.version,
+		}
+		if decl.forcedBy != nil {
+			m.Reason = fmt.Sprintf("refers to %s, which needs go1.%d and cannot be stubbed", decl.forcedBy.name, decl.forcedBy.version)
+		} else {
+			m.Reason = decl.reason.String()
+		}
+
+		needStub := decl.exported
+		for user := range decl.usedBy {
+			if !user.move {
+				needStub = true
+			}
+		}
+		if needStub {
+			if decl.stubbable {
+				stub, err := gateStub(pkg.Fset, decl)
+				if err != nil {
+					return nil, nil, err
+				}
+				m.Stub = stub
+			} else {
+				m.Missing = true
+			}
+		}
+
+		moves = append(moves, m)
+	}
+
+	return moves, result, nil
+}
+
+// Function gateDecls collects the top-level declarations in files,
+// and maps their objects to them.
+func gateDecls(pkg *packages.Package, files []*ast.File) ([]*gateDecl, map[types.Object]*gateDecl) {
+	var (
+		decls []*gateDecl
+		byObj = make(map[types.Object]*gateDecl)
+	)
+
+	add := func(node ast.Node, name string, exported, canStub bool, sig []ast.Node, ids ...*ast.Ident) {
+		decl := &gateDecl{
+			node:     node,
+			sig:      sig,
+			name:     name,
+			pos:      pkg.Fset.Position(node.Pos()),
+			exported: exported,
+			usedBy:   make(map[*gateDecl]bool),
+			canStub:  canStub,
+		}
+		decls = append(decls, decl)
+		for _, id := range ids {
+			if obj := pkg.TypesInfo.Defs[id]; obj != nil {
+				byObj[obj] = decl
+			}
+		}
+	}
+
+	for _, file := range files {
+		for _, d := range file.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				var (
+					name     = d.Name.Name
+					exported = d.Name.IsExported()
+					sig      = []ast.Node{d.Type}
+				)
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					recv := d.Recv.List[0].Type
+					sig = append(sig, recv)
+					typename, ptr := receiverTypeName(recv)
+					if ptr {
+						name = fmt.Sprintf("(*%s).%s", typename, name)
+					} else {
+						name = fmt.Sprintf("%s.%s", typename, name)
+					}
+					exported = exported && ast.IsExported(typename)
+				}
+				add(d, name, exported, true, sig, d.Name)
+
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						add(spec, spec.Name.Name, spec.Name.IsExported(), false, []ast.Node{spec}, spec.Name)
+
+					case *ast.ValueSpec:
+						var (
+							names    []string
+							exported bool
+							sig      = []ast.Node{spec}
+						)
+						for _, id := range spec.Names {
+							names = append(names, id.Name)
+							exported = exported || id.IsExported()
+						}
+						// Only a typed var can have a stub:
+						// the same var with no initializer.
+						canStub := spec.Type != nil && d.Tok == token.VAR
+						if canStub {
+							sig = []ast.Node{spec.Type}
+						}
+						add(spec, strings.Join(names, ", "), exported, canStub, sig, spec.Names...)
+					}
+				}
+			}
+		}
+	}
+
+	return decls, byObj
+}
+
+func receiverTypeName(expr ast.Expr) (name string, ptr bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr, ptr = star.X, true
+	}
+	switch x := expr.(type) {
+	case *ast.IndexExpr:
+		expr = x.X
+	case *ast.IndexListExpr:
+		expr = x.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name, ptr
+	}
+	return "?", ptr
+}
+
+// Function gateUses finds the other declarations that the given nodes refer to.
+func gateUses(info *types.Info, byObj map[types.Object]*gateDecl, decl *gateDecl, nodes []ast.Node) map[*gateDecl]bool {
+	result := make(map[*gateDecl]bool)
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := info.Uses[id]
+			if inst, ok := obj.(*types.Func); ok {
+				obj = inst.Origin()
+			}
+			if dep, ok := byObj[obj]; ok && dep != decl {
+				result[dep] = true
+			}
+			return true
+		})
+	}
+	return result
+}
+
+func containing(decls []*gateDecl, pos token.Pos) *gateDecl {
+	for _, decl := range decls {
+		if decl.node.Pos() <= pos && pos < decl.node.End() {
+			return decl
+		}
+	}
+	return nil
+}
+
+// Function gateStub produces a fallback stub for a declaration.
+// A function's stub panics;
+// a variable's is its zero value.
+func gateStub(fset *token.FileSet, decl *gateDecl) (string, error) {
+	var buf bytes.Buffer
+
+	switch node := decl.node.(type) {
+	case *ast.FuncDecl:
+		stub := *node
+		stub.Doc = nil
+		stub.Body = nil
+		if err := printer.Fprint(&buf, fset, &stub); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, " {\n\tpanic(%q)\n}", fmt.Sprintf("%s requires Go 1.%d", decl.name, decl.version))
+
+	case *ast.ValueSpec:
+		buf.WriteString("var ")
+		for i, id := range node.Names {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(id.Name)
+		}
+		buf.WriteString(" ")
+		if err := printer.Fprint(&buf, fset, node.Type); err != nil {
+			return "", err
+		}
+	}
+
+	return buf.String(), nil
+}
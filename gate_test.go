@@ -0,0 +1,61 @@
+package mingo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGatePlanner(t *testing.T) {
+	g := GatePlanner{Target: 20}
+	plan, err := g.Run("testdata/gate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Result.Version() != 18 {
+		t.Errorf("got result %s, want 18", plan.Result)
+	}
+
+	type want struct {
+		name     string
+		version  int
+		stub     string
+		missing  bool
+		forcedBy string
+	}
+	wants := []want{{
+		name:    "Has",
+		version: 21,
+		stub:    "func Has(words []string, word string) bool {\n\tpanic(\"Has requires Go 1.21\")\n}",
+	}, {
+		name:    "Lines",
+		version: 24,
+		missing: true,
+	}, {
+		name:     "seq",
+		version:  24,
+		forcedBy: "Lines",
+	}}
+
+	if len(plan.Moves) != len(wants) {
+		t.Fatalf("got %d moves, want %d: %v", len(plan.Moves), len(wants), plan.Moves)
+	}
+	for i, w := range wants {
+		m := plan.Moves[i]
+		if m.Name != w.name {
+			t.Errorf("move %d: got name %s, want %s", i, m.Name, w.name)
+		}
+		if m.Version != w.version {
+			t.Errorf("move %d (%s): got version %d, want %d", i, m.Name, m.Version, w.version)
+		}
+		if m.Stub != w.stub {
+			t.Errorf("move %d (%s): got stub %q, want %q", i, m.Name, m.Stub, w.stub)
+		}
+		if m.Missing != w.missing {
+			t.Errorf("move %d (%s): got missing %v, want %v", i, m.Name, m.Missing, w.missing)
+		}
+		if w.forcedBy != "" && !strings.Contains(m.Reason, "refers to "+w.forcedBy) {
+			t.Errorf("move %d (%s): got reason %q, want it to refer to %s", i, m.Name, m.Reason, w.forcedBy)
+		}
+	}
+}
@@ -5,6 +5,7 @@ go 1.27
 require (
 	github.com/bobg/errors v1.3.0
 	github.com/bobg/go-generics/v4 v4.2.0
+	github.com/golangci/plugin-module-register v0.1.2
 	golang.org/x/mod v0.39.0
 	golang.org/x/tools v0.49.0
 )
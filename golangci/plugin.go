@@ -0,0 +1,79 @@
+// Package golangci registers mingo as a golangci-lint module plugin.
+//
+// To use it, name this package in the plugins section of .custom-gcl.yml:
+//
+//	plugins:
+//	  - module: github.com/bobg/mingo
+//	    import: github.com/bobg/mingo/golangci
+//
+// and enable the "mingo" linter in .golangci.yml:
+//
+//	linters:
+//	  enable:
+//	    - mingo
+//	  settings:
+//	    custom:
+//	      mingo:
+//	        type: module
+//	        settings:
+//	          target: 21
+package golangci
+
+import (
+	"strconv"
+
+	"github.com/bobg/errors"
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/bobg/mingo"
+)
+
+func init() {
+	register.Plugin("mingo", New)
+}
+
+// Settings are the settings for the plugin.
+type Settings struct {
+	// Target is the minor version of Go 1.x above which to report findings.
+	// The default is the version declared in go.mod.
+	Target int `json:"target"`
+
+	// API is a directory of Go stdlib API history files
+	// (default: $GOROOT/api).
+	API string `json:"api"`
+}
+
+// Plugin is the mingo plugin for golangci-lint.
+type Plugin struct {
+	settings Settings
+}
+
+// New creates a [Plugin] from the settings in .golangci.yml.
+func New(conf any) (register.LinterPlugin, error) {
+	settings, err := register.DecodeSettings[Settings](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Plugin{settings: settings}, nil
+}
+
+// BuildAnalyzers implements [register.LinterPlugin].
+func (p *Plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	s := &mingo.Scanner{HistDir: p.settings.API}
+	a, err := s.Analyzer()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating analyzer")
+	}
+	if p.settings.Target > 0 {
+		if err := a.Flags.Set("target", strconv.Itoa(p.settings.Target)); err != nil {
+			return nil, errors.Wrap(err, "setting target")
+		}
+	}
+	return []*analysis.Analyzer{a}, nil
+}
+
+// GetLoadMode implements [register.LinterPlugin].
+func (p *Plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
@@ -0,0 +1,36 @@
+package golangci
+
+import (
+	"testing"
+
+	"github.com/golangci/plugin-module-register/register"
+)
+
+func TestPlugin(t *testing.T) {
+	newPlugin, err := register.GetPlugin("mingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newPlugin(map[string]any{"target": 21})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.GetLoadMode(); got != register.LoadModeTypesInfo {
+		t.Errorf("got load mode %s, want %s", got, register.LoadModeTypesInfo)
+	}
+
+	analyzers, err := p.BuildAnalyzers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analyzers) != 1 {
+		t.Fatalf("got %d analyzers, want 1", len(analyzers))
+	}
+	if got := analyzers[0].Flags.Lookup("target").Value.String(); got != "21" {
+		t.Errorf("got target %s, want 21", got)
+	}
+
+	if _, err := newPlugin(map[string]any{"bogus": true}); err == nil {
+		t.Error("got no error for unknown setting")
+	}
+}
@@ -0,0 +1,196 @@
+package mingo
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// Versions of Go whose go command added the go.mod and go.work features that mingo reports.
+const (
+	goModRetract    = 16
+	goModDeprecated = 17
+	goWork          = 18
+	goModToolchain  = 21 // also patch-level and prerelease go lines, like go 1.21.0 and go 1.21rc1
+	goModGodebug    = 23
+	goModTool       = 24
+	goModIgnore     = 25
+)
+
+// Older go commands accept only go lines of this form.
+var oldGoLine = regexp.MustCompile(`^1\.(0|[1-9][0-9]*)$`)
+
+// Method scanGoMod reports the directives in the go.mod file at gomodPath,
+// and in the go.work file that applies to it, if any,
+// that older go commands do not understand.
+func (st *scanState) scanGoMod(gomodPath string) error {
+	data, err := os.ReadFile(gomodPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading go.mod at %s", gomodPath)
+	}
+	results, err := goModResults(gomodPath, data)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if st.result(r) {
+			return nil
+		}
+	}
+
+	workPath := findGoWork(filepath.Dir(gomodPath))
+	if workPath == "" {
+		return nil
+	}
+	data, err = os.ReadFile(workPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading go.work at %s", workPath)
+	}
+	results, err = goWorkResults(workPath, data)
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if st.result(r) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Function goModResults returns the findings for the contents of a go.mod file.
+func goModResults(filename string, data []byte) ([]posResult, error) {
+	// Not ParseLax, which skips the directives that matter only in the main module,
+	// like toolchain and godebug.
+	f, err := modfile.Parse(filename, data, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing go.mod at %s", filename)
+	}
+
+	var results []posResult
+	add := func(version int, line *modfile.Line, desc, rule string) {
+		results = append(results, posResult{
+			version: version,
+			pos:     modfilePosition(filename, line.Start),
+			desc:    desc,
+			rule:    rule,
+		})
+	}
+
+	if f.Module != nil && f.Module.Deprecated != "" {
+		if c, ok := deprecatedComment(f.Module.Syntax); ok {
+			results = append(results, posResult{
+				version: goModDeprecated,
+				pos:     modfilePosition(filename, c.Start),
+				desc:    "Deprecated comment on module directive",
+				rule:    "gomod-deprecated",
+			})
+		}
+	}
+	if f.Go != nil && !oldGoLine.MatchString(f.Go.Version) {
+		add(goModToolchain, f.Go.Syntax, fmt.Sprintf("go directive with version %s", f.Go.Version), "gomod-go-version")
+	}
+	if f.Toolchain != nil {
+		add(goModToolchain, f.Toolchain.Syntax, "toolchain directive", "gomod-toolchain")
+	}
+	for _, g := range f.Godebug {
+		add(goModGodebug, g.Syntax, "godebug directive", "gomod-godebug")
+	}
+	for _, r := range f.Retract {
+		add(goModRetract, r.Syntax, "retract directive", "gomod-retract")
+	}
+	for _, t := range f.Tool {
+		add(goModTool, t.Syntax, "tool directive", "gomod-tool")
+	}
+	for _, ig := range f.Ignore {
+		add(goModIgnore, ig.Syntax, "ignore directive", "gomod-ignore")
+	}
+
+	return results, nil
+}
+
+// Function goWorkResults returns the findings for the contents of a go.work file.
+// Workspaces themselves need Go 1.18.
+func goWorkResults(filename string, data []byte) ([]posResult, error) {
+	f, err := modfile.ParseWork(filename, data, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing go.work at %s", filename)
+	}
+
+	results := []posResult{{
+		version: goWork,
+		pos:     token.Position{Filename: filename, Line: 1, Column: 1},
+		desc:    "go.work file",
+		rule:    "gowork",
+	}}
+	add := func(version int, line *modfile.Line, desc, rule string) {
+		results = append(results, posResult{
+			version: version,
+			pos:     modfilePosition(filename, line.Start),
+			desc:    desc,
+			rule:    rule,
+		})
+	}
+
+	if f.Go != nil && !oldGoLine.MatchString(f.Go.Version) {
+		add(goModToolchain, f.Go.Syntax, fmt.Sprintf("go directive with version %s", f.Go.Version), "gowork-go-version")
+	}
+	if f.Toolchain != nil {
+		add(goModToolchain, f.Toolchain.Syntax, "toolchain directive", "gowork-toolchain")
+	}
+	for _, g := range f.Godebug {
+		add(goModGodebug, g.Syntax, "godebug directive", "gowork-godebug")
+	}
+
+	return results, nil
+}
+
+func modfilePosition(filename string, pos modfile.Position) token.Position {
+	return token.Position{Filename: filename, Offset: pos.Byte, Line: pos.Line, Column: pos.LineRune}
+}
+
+// Function deprecatedComment finds the "// Deprecated:" comment on a module directive.
+func deprecatedComment(line *modfile.Line) (modfile.Comment, bool) {
+	for _, c := range slices.Concat(line.Before, line.Suffix) {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Token, "//"))
+		if strings.HasPrefix(text, "Deprecated:") {
+			return c, true
+		}
+	}
+	return modfile.Comment{}, false
+}
+
+// Function findGoWork returns the go.work file that the go command uses for a module in dir,
+// or "" if there is none.
+// As in the go command, $GOWORK overrides the search
+// (and GOWORK=off disables workspaces).
+func findGoWork(dir string) string {
+	if gowork := os.Getenv("GOWORK"); gowork != "" {
+		if gowork == "off" {
+			return ""
+		}
+		return gowork
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, "go.work")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
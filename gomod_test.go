@@ -0,0 +1,129 @@
+package mingo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGoModResults(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want []string // rule@line
+		work bool
+	}{{
+		name: "plain",
+		src:  "module x.y/z\n\ngo 1.20\n\nrequire a.b/c v1.2.3\n",
+	}, {
+		name: "patch",
+		src:  "module x.y/z\n\ngo 1.21.0\n",
+		want: []string{"gomod-go-version@3"},
+	}, {
+		name: "prerelease",
+		src:  "module x.y/z\n\ngo 1.21rc1\n",
+		want: []string{"gomod-go-version@3"},
+	}, {
+		name: "directives",
+		src:  "module x.y/z\n\ngo 1.22\n\ntoolchain go1.22.3\n\ngodebug (\n\tpanicnil=1\n\thttp2client=0\n)\n\nretract [v1.0.0, v1.0.5]\n\ntool a.b/c/cmd/d\n\nignore ./node_modules\n",
+		want: []string{"gomod-toolchain@5", "gomod-godebug@8", "gomod-godebug@9", "gomod-retract@12", "gomod-tool@14", "gomod-ignore@16"},
+	}, {
+		name: "deprecated before",
+		src:  "// Deprecated: use a.b/c.\nmodule x.y/z\n\ngo 1.20\n",
+		want: []string{"gomod-deprecated@1"},
+	}, {
+		name: "deprecated suffix",
+		src:  "module x.y/z // Deprecated: use a.b/c.\n\ngo 1.20\n",
+		want: []string{"gomod-deprecated@1"},
+	}, {
+		name: "not deprecated",
+		src:  "// This module is not Deprecated: really.\nmodule x.y/z\n\ngo 1.20\n",
+	}, {
+		name: "work",
+		src:  "go 1.18\n\nuse ./a\n",
+		want: []string{"gowork@1"},
+		work: true,
+	}, {
+		name: "work directives",
+		src:  "go 1.23.0\n\ntoolchain go1.23.1\n\ngodebug asynctimerchan=1\n\nuse ./a\n",
+		want: []string{"gowork@1", "gowork-go-version@1", "gowork-toolchain@3", "gowork-godebug@5"},
+		work: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				results []posResult
+				err     error
+			)
+			if tc.work {
+				results, err = goWorkResults("go.work", []byte(tc.src))
+			} else {
+				results, err = goModResults("go.mod", []byte(tc.src))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, fmt.Sprintf("%s@%d", r.rule, r.pos.Line))
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindGoWork(t *testing.T) {
+	t.Setenv("GOWORK", "")
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gowork := filepath.Join(dir, "a", "go.work")
+	if err := os.WriteFile(gowork, []byte("go 1.18\n\nuse ./b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := findGoWork(sub); got != gowork {
+		t.Errorf("got %s, want %s", got, gowork)
+	}
+
+	t.Setenv("GOWORK", "off")
+	if got := findGoWork(sub); got != "" {
+		t.Errorf("with GOWORK=off, got %s, want none", got)
+	}
+}
+
+func TestScanGoMod(t *testing.T) {
+	t.Setenv("GOWORK", "off")
+
+	s := Scanner{GoMod: true}
+	res, err := s.ScanDir("testdata/gomod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, ok := res.(posResult)
+	if !ok {
+		t.Fatalf("got %T, want posResult", res)
+	}
+	if pr.version != goModTool || pr.rule != "gomod-tool" {
+		t.Errorf("got %s, want tool directive", pr)
+	}
+	if pr.pos.Line != 15 || filepath.Base(pr.pos.Filename) != "go.mod" {
+		t.Errorf("got position %s, want go.mod:15", pr.pos)
+	}
+
+	s.GoMod = false
+	res, err = s.ScanDir("testdata/gomod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version() != 0 {
+		t.Errorf("without GoMod, got %s, want 0", res)
+	}
+}
@@ -0,0 +1,311 @@
+package mingo
+
+import "strings"
+
+// Type helper is a replacement, for older versions of Go,
+// for a stdlib function or constant.
+type helper struct {
+	pkgpath, name string // the stdlib identifier, e.g. "strings" and "Cut"
+
+	// The minor version of Go 1.x needed by the replacement code itself
+	// (e.g. 18 for a generic function).
+	needs int
+
+	imports []string // the packages imported by the replacement code
+
+	// The replacement declaration.
+	// Its first %[1]s is the name to give it.
+	code string
+}
+
+// Method localName is the name for the helper in the package using it,
+// e.g. stringsCut for strings.Cut.
+func (h helper) localName() string {
+	pkgname := h.pkgpath[strings.LastIndex(h.pkgpath, "/")+1:]
+	return pkgname + h.name
+}
+
+func findHelper(pkgpath, name string) (helper, bool) {
+	for _, h := range helperTable {
+		if h.pkgpath == pkgpath && h.name == name {
+			return h, true
+		}
+	}
+	return helper{}, false
+}
+
+var helperTable = []helper{{
+	pkgpath: "strings",
+	name:    "ReplaceAll",
+	imports: []string{"strings"},
+	code: `func %[1]s(s, old, new string) string {
+	return strings.Replace(s, old, new, -1)
+}`,
+}, {
+	pkgpath: "bytes",
+	name:    "ReplaceAll",
+	imports: []string{"bytes"},
+	code: `func %[1]s(s, old, new []byte) []byte {
+	return bytes.Replace(s, old, new, -1)
+}`,
+}, {
+	pkgpath: "io",
+	name:    "ReadAll",
+	imports: []string{"io", "io/ioutil"},
+	code: `func %[1]s(r io.Reader) ([]byte, error) {
+	return ioutil.ReadAll(r)
+}`,
+}, {
+	pkgpath: "io",
+	name:    "NopCloser",
+	imports: []string{"io", "io/ioutil"},
+	code: `func %[1]s(r io.Reader) io.ReadCloser {
+	return ioutil.NopCloser(r)
+}`,
+}, {
+	pkgpath: "io",
+	name:    "Discard",
+	imports: []string{"io/ioutil"},
+	code:    `var %[1]s = ioutil.Discard`,
+}, {
+	pkgpath: "os",
+	name:    "ReadFile",
+	imports: []string{"io/ioutil"},
+	code: `func %[1]s(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}`,
+}, {
+	pkgpath: "os",
+	name:    "WriteFile",
+	imports: []string{"io/ioutil", "os"},
+	code: `func %[1]s(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}`,
+}, {
+	pkgpath: "strings",
+	name:    "Clone",
+	code: `func %[1]s(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	b := make([]byte, len(s))
+	copy(b, s)
+	return string(b)
+}`,
+}, {
+	pkgpath: "strings",
+	name:    "Cut",
+	imports: []string{"strings"},
+	code: `func %[1]s(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}`,
+}, {
+	pkgpath: "bytes",
+	name:    "Cut",
+	imports: []string{"bytes"},
+	code: `func %[1]s(s, sep []byte) (before, after []byte, found bool) {
+	if i := bytes.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, nil, false
+}`,
+}, {
+	pkgpath: "fmt",
+	name:    "Append",
+	imports: []string{"fmt"},
+	code: `func %[1]s(b []byte, a ...interface{}) []byte {
+	return append(b, fmt.Sprint(a...)...)
+}`,
+}, {
+	pkgpath: "fmt",
+	name:    "Appendf",
+	imports: []string{"fmt"},
+	code: `func %[1]s(b []byte, format string, a ...interface{}) []byte {
+	return append(b, fmt.Sprintf(format, a...)...)
+}`,
+}, {
+	pkgpath: "fmt",
+	name:    "Appendln",
+	imports: []string{"fmt"},
+	code: `func %[1]s(b []byte, a ...interface{}) []byte {
+	return append(b, fmt.Sprintln(a...)...)
+}`,
+}, {
+	pkgpath: "bytes",
+	name:    "Clone",
+	code: `func %[1]s(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}`,
+}, {
+	pkgpath: "strings",
+	name:    "CutPrefix",
+	imports: []string{"strings"},
+	code: `func %[1]s(s, prefix string) (after string, found bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}`,
+}, {
+	pkgpath: "strings",
+	name:    "CutSuffix",
+	imports: []string{"strings"},
+	code: `func %[1]s(s, suffix string) (before string, found bool) {
+	if !strings.HasSuffix(s, suffix) {
+		return s, false
+	}
+	return s[:len(s)-len(suffix)], true
+}`,
+}, {
+	pkgpath: "bytes",
+	name:    "CutPrefix",
+	imports: []string{"bytes"},
+	code: `func %[1]s(s, prefix []byte) (after []byte, found bool) {
+	if !bytes.HasPrefix(s, prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}`,
+}, {
+	pkgpath: "bytes",
+	name:    "CutSuffix",
+	imports: []string{"bytes"},
+	code: `func %[1]s(s, suffix []byte) (before []byte, found bool) {
+	if !bytes.HasSuffix(s, suffix) {
+		return s, false
+	}
+	return s[:len(s)-len(suffix)], true
+}`,
+}, {
+	pkgpath: "time",
+	name:    "DateTime",
+	code:    `const %[1]s = "2006-01-02 15:04:05"`,
+}, {
+	pkgpath: "time",
+	name:    "DateOnly",
+	code:    `const %[1]s = "2006-01-02"`,
+}, {
+	pkgpath: "time",
+	name:    "TimeOnly",
+	code:    `const %[1]s = "15:04:05"`,
+}, {
+	pkgpath: "sync",
+	name:    "OnceFunc",
+	imports: []string{"sync"},
+	code: `func %[1]s(f func()) func() {
+	var (
+		once  sync.Once
+		valid bool
+		p     interface{}
+	)
+	g := func() {
+		defer func() {
+			p = recover()
+			if !valid {
+				panic(p)
+			}
+		}()
+		f()
+		f = nil
+		valid = true
+	}
+	return func() {
+		once.Do(g)
+		if !valid {
+			panic(p)
+		}
+	}
+}`,
+}, {
+	pkgpath: "slices",
+	name:    "Contains",
+	needs:   18,
+	code: `func %[1]s[S ~[]E, E comparable](s S, v E) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}`,
+}, {
+	pkgpath: "slices",
+	name:    "ContainsFunc",
+	needs:   18,
+	code: `func %[1]s[S ~[]E, E any](s S, f func(E) bool) bool {
+	for _, x := range s {
+		if f(x) {
+			return true
+		}
+	}
+	return false
+}`,
+}, {
+	pkgpath: "slices",
+	name:    "Index",
+	needs:   18,
+	code: `func %[1]s[S ~[]E, E comparable](s S, v E) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}`,
+}, {
+	pkgpath: "slices",
+	name:    "IndexFunc",
+	needs:   18,
+	code: `func %[1]s[S ~[]E, E any](s S, f func(E) bool) int {
+	for i, x := range s {
+		if f(x) {
+			return i
+		}
+	}
+	return -1
+}`,
+}, {
+	pkgpath: "slices",
+	name:    "Equal",
+	needs:   18,
+	code: `func %[1]s[S ~[]E, E comparable](s1, s2 S) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+	for i := range s1 {
+		if s1[i] != s2[i] {
+			return false
+		}
+	}
+	return true
+}`,
+}, {
+	pkgpath: "slices",
+	name:    "Reverse",
+	needs:   18,
+	code: `func %[1]s[S ~[]E, E any](s S) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}`,
+}, {
+	pkgpath: "maps",
+	name:    "Clone",
+	needs:   18,
+	code: `func %[1]s[M ~map[K]V, K comparable, V any](m M) M {
+	if m == nil {
+		return nil
+	}
+	r := make(M, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}`,
+}}
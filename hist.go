@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"embed"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -22,6 +24,7 @@ import (
 type history struct {
 	pkgs map[string]pkgHistory // maps package paths to package histories
 	max  int                   // the highest minor version of Go seen
+	next int                   // if nonzero, the provisional version whose API comes from the next/ subdirectory
 }
 
 func (h history) lookup(pkgpath, id, typ string) int {
@@ -31,6 +34,12 @@ func (h history) lookup(pkgpath, id, typ string) int {
 	return 0
 }
 
+// Method provisional tells whether v is the not-yet-released version
+// whose history comes from the next/ subdirectory.
+func (h history) provisional(v int) bool {
+	return h.next > 0 && v == h.next
+}
+
 // Type pkgHistory is the history of a single Go stdlib package.
 type pkgHistory struct {
 	// Maps top-level identifiers to the minor version of Go at which they were first introduced.
@@ -93,6 +102,11 @@ var apifilenameRegex = regexp.MustCompile(`^go1\.(\d+)\.txt$`)
 // Function readHistFS reads the history of the Go stdlib
 // from the sequence of go1.*.txt files
 // in the given directory within the given filesystem.
+//
+// If the directory has a next/ subdirectory
+// (as it does in a GOROOT built from tip or a release candidate),
+// the *.txt files in it are read as the history of the unreleased version
+// one higher than the highest go1.*.txt file.
 func readHistFS(fsys fs.FS, dir string) (*history, error) {
 	h := &history{
 		pkgs: make(map[string]pkgHistory),
@@ -123,9 +137,50 @@ func readHistFS(fsys fs.FS, dir string) (*history, error) {
 		}
 	}
 
+	if err := readHistNext(h, fsys, path.Join(dir, "next")); err != nil {
+		return nil, errors.Wrap(err, "reading next-version history")
+	}
+
 	return h, nil
 }
 
+// Function readHistNext reads the API fragments in the next/ directory,
+// if there is one,
+// as the history of version h.max+1.
+func readHistNext(h *history, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	v := h.max + 1
+
+	var found bool
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		base := entry.Name()
+		if !strings.HasSuffix(base, ".txt") {
+			continue
+		}
+		if err := readHistVersion(h, fsys, path.Join(dir, base), v); err != nil {
+			return errors.Wrapf(err, "reading %s", base)
+		}
+		found = true
+	}
+
+	if found {
+		h.max = v
+		h.next = v
+	}
+
+	return nil
+}
+
 func goroot() string {
 	if g := os.Getenv("GOROOT"); g != "" {
 		return g
@@ -142,7 +197,14 @@ func readHistVersion(h *history, fsys fs.FS, filename string, v int) error {
 	}
 	defer f.Close()
 
-	sc := bufio.NewScanner(f)
+	return errors.Wrapf(readHistLines(h, f, v), "scanning %s", filename)
+}
+
+// Function readHistLines adds the API features listed in r,
+// one per line,
+// to h as version v.
+func readHistLines(h *history, r io.Reader, v int) error {
+	sc := bufio.NewScanner(r)
 	for sc.Scan() {
 		line := sc.Text()
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -180,7 +242,7 @@ func readHistVersion(h *history, fsys fs.FS, filename string, v int) error {
 			return fmt.Errorf("unrecognized line %s", line)
 		}
 	}
-	return errors.Wrapf(sc.Err(), "scanning %s", filename)
+	return sc.Err()
 }
 
 func match2(h *history, pkgpath, id string, v int) {
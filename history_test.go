@@ -3,6 +3,7 @@ package mingo
 import (
 	"fmt"
 	"testing"
+	"testing/fstest"
 )
 
 func TestHistory(t *testing.T) {
@@ -58,3 +59,37 @@ func TestHistory(t *testing.T) {
 		})
 	}
 }
+
+func TestHistoryNext(t *testing.T) {
+	fsys := fstest.MapFS{
+		"api/go1.txt":        {Data: []byte("pkg foo, func A() int\n")},
+		"api/go1.1.txt":      {Data: []byte("pkg foo, func B() int\n")},
+		"api/next/12345.txt": {Data: []byte("pkg foo, func C() int #12345\npkg foo, method (*T) M() #12345\n")},
+	}
+
+	h, err := readHistFS(fsys, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.max != 2 {
+		t.Errorf("got max %d, want 2", h.max)
+	}
+	if h.next != 2 {
+		t.Errorf("got next %d, want 2", h.next)
+	}
+	if got := h.lookup("foo", "B", ""); got != 1 {
+		t.Errorf("got %d for foo.B, want 1", got)
+	}
+	if got := h.lookup("foo", "C", ""); got != 2 {
+		t.Errorf("got %d for foo.C, want 2", got)
+	}
+	if got := h.lookup("foo", "M", "T"); got != 2 {
+		t.Errorf("got %d for foo.T.M, want 2", got)
+	}
+	if h.provisional(1) {
+		t.Error("version 1 reported as provisional")
+	}
+	if !h.provisional(2) {
+		t.Error("version 2 not reported as provisional")
+	}
+}
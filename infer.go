@@ -0,0 +1,75 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// Go 1.21 made type inference much more powerful.
+// It can infer type arguments from the type of the variable
+// to which a generic function is assigned,
+// from generic functions (even partially instantiated ones) passed as arguments,
+// from the methods of a type passed for an interface-typed parameter,
+// and so on.
+// Rather than model all of that,
+// mingo type-checks a package again as Go 1.20 would
+// and looks for the errors that the new inference avoids.
+
+// Method inferenceErrors returns the positions of the type-inference failures
+// in the package's files when type-checked with Go 1.20 rules.
+// It returns nil when the package instantiates no generics,
+// or when it was loaded without type information for its imports.
+func (p *pkgScanner) inferenceErrors(files []*ast.File) map[token.Pos]bool {
+	if p.pkg == nil || p.info == nil || len(p.info.Instances) == 0 {
+		return nil
+	}
+
+	result := make(map[token.Pos]bool)
+	p.recheck(files, "go1.20", func(err types.Error) {
+		if isInferenceError(err.Msg) {
+			result[err.Pos] = true
+		}
+	})
+	return result
+}
+
+// Function isInferenceError tells whether msg,
+// from the type checker with Go 1.20 rules,
+// is about type inference that Go 1.21 added.
+func isInferenceError(msg string) bool {
+	if strings.Contains(msg, "cannot infer") {
+		return true
+	}
+	return strings.Contains(msg, "instantiated function") && strings.HasSuffix(msg, "requires go1.21 or later")
+}
+
+func matchGenericInference(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	if len(c.inference) == 0 {
+		return token.NoPos, false
+	}
+
+	// The type checker reports an inference error
+	// at the start of an expression,
+	// or at the bracket of a partial instantiation.
+	var pos token.Pos
+	switch node := node.(type) {
+	case *ast.IndexExpr:
+		pos = node.Lbrack
+	case *ast.IndexListExpr:
+		pos = node.Lbrack
+	case ast.Expr:
+		pos = node.Pos()
+	default:
+		return token.NoPos, false
+	}
+	if !c.inference[pos] {
+		return token.NoPos, false
+	}
+
+	// Report each error once,
+	// at the outermost expression.
+	delete(c.inference, pos)
+	return node.Pos(), true
+}
@@ -0,0 +1,1309 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+)
+
+// These are mingo's builtin language rules.
+// Each Match function examines a single node;
+// the scan visits every node in every file.
+var langRules = []Rule{{
+	ID:      "missing-final-return",
+	Version: 1,
+	Desc:    "function body with no final return statement",
+	Link:    "https://go.dev/doc/go1.1#return",
+	Match:   matchMissingFinalReturn,
+}, {
+	ID:      "method-value",
+	Version: 1,
+	Desc:    "method used as value",
+	Link:    "https://go.dev/doc/go1.1#method_values",
+	Match:   matchMethodValue,
+}, {
+	ID:      "range-without-vars",
+	Version: 4,
+	Desc:    `variable-free "for range" statement`,
+	Link:    "https://go.dev/doc/go1.4#forrange",
+	Match:   matchRangeWithoutVars,
+}, {
+	ID:      "elided-composite-key-type",
+	Version: 5,
+	Desc:    "composite literal with composite-type key and no explicit type",
+	Link:    "https://go.dev/doc/go1.5#language",
+	Match:   matchElidedCompositeKeyType,
+}, {
+	ID:      "three-index-slice",
+	Version: 5,
+	Desc:    "slice expression with 3 indices",
+	Link:    "https://go.dev/ref/spec#Slice_expressions",
+	Match:   matchThreeIndexSlice,
+}, {
+	ID:      "struct-tag-conversion",
+	Version: 8,
+	Desc:    "conversion between structs with differing struct tags",
+	Link:    "https://go.dev/doc/go1.8#language",
+	Match:   matchStructTagConversion,
+}, {
+	ID:      "type-alias",
+	Version: 9,
+	Desc:    "type alias",
+	Link:    "https://go.dev/doc/go1.9#language",
+	Match:   matchTypeAlias,
+}, {
+	ID:       "fmt-errorf-wrap",
+	Version:  13,
+	Desc:     "%w verb in fmt.Errorf format",
+	Link:     "https://go.dev/doc/go1.13#error_wrapping",
+	Match:    matchErrorfWrap,
+	Semantic: true,
+}, {
+	ID:       "fmt-hex-float",
+	Version:  13,
+	Desc:     "%x or %X verb with floating-point or complex operand",
+	Link:     "https://go.dev/doc/go1.13#fmt",
+	Match:    matchFormatHexFloat,
+	Semantic: true,
+}, {
+	ID:       "fmt-octal-prefix",
+	Version:  13,
+	Desc:     "%O verb",
+	Link:     "https://go.dev/doc/go1.13#fmt",
+	Match:    matchFormatOctalO,
+	Semantic: true,
+}, {
+	ID:      "expanded-numeric-literal",
+	Version: 13,
+	Desc:    "expanded numeric literal",
+	Link:    "https://go.dev/doc/go1.13#language",
+	Match:   matchExpandedNumericLiteral,
+	Fix:     fixExpandedNumericLiteral,
+}, {
+	ID:      "signed-shift-count",
+	Version: 13,
+	Desc:    "signed shift count",
+	Link:    "https://go.dev/doc/go1.13#language",
+	Match:   matchSignedShiftCount,
+}, {
+	ID:      "overlapping-interfaces",
+	Version: 14,
+	Desc:    "interface defined in terms of overlapping method sets",
+	Link:    "https://go.dev/doc/go1.14#language",
+	Match:   matchOverlappingInterfaces,
+}, {
+	ID:      "go-embed",
+	Version: 16,
+	Desc:    "//go:embed directive",
+	Link:    "https://pkg.go.dev/embed",
+	Match:   matchDirective("embed"),
+}, {
+	ID:      "go-build-line",
+	Version: 17,
+	Desc:    "//go:build line without matching // +build line",
+	Link:    "https://go.dev/doc/go1.17#build-lines",
+	Match:   matchGoBuild,
+}, {
+	ID:       "time-layout-comma",
+	Version:  17,
+	Desc:     "comma before fractional seconds in time layout",
+	Link:     "https://go.dev/doc/go1.17#time",
+	Match:    matchTimeLayoutComma,
+	Semantic: true,
+}, {
+	ID:      "slice-to-array-pointer",
+	Version: 17,
+	Desc:    "conversion from slice to array pointer",
+	Link:    "https://go.dev/doc/go1.17#language",
+	Match:   matchSliceToArrayPointer,
+}, {
+	ID:      "unsafe-add-slice",
+	Version: 17,
+	Desc:    "use of unsafe.Add or unsafe.Slice builtin",
+	Link:    "https://go.dev/doc/go1.17#language",
+	Match:   matchUnsafeBuiltin("Add", "Slice"),
+}, {
+	ID:      "go-embed-all",
+	Version: 18,
+	Desc:    `"all:" prefix in //go:embed pattern`,
+	Link:    "https://pkg.go.dev/embed#hdr-Directives",
+	Match:   matchEmbedAll,
+}, {
+	ID:      "any",
+	Version: 18,
+	Desc:    `"any" builtin`,
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchAny,
+	Fix:     fixAny,
+}, {
+	ID:      "generic-func-decl",
+	Version: 18,
+	Desc:    "generic func decl",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchGenericFuncDecl,
+}, {
+	ID:      "generic-func-lit",
+	Version: 18,
+	Desc:    "generic function literal",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchGenericFuncLit,
+}, {
+	ID:      "generic-func-type",
+	Version: 18,
+	Desc:    "generic function type",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchGenericFuncType,
+}, {
+	ID:      "generic-instantiation",
+	Version: 18,
+	Desc:    "generic instantiation",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchGenericInstantiation,
+}, {
+	ID:      "generic-type-decl",
+	Version: 18,
+	Desc:    "generic type decl",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchGenericTypeDecl,
+}, {
+	ID:      "interface-type-terms",
+	Version: 18,
+	Desc:    "interface containing type terms",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchInterfaceTypeTerms,
+}, {
+	ID:      "tilde",
+	Version: 18,
+	Desc:    "tilde operator",
+	Link:    "https://go.dev/doc/go1.18#generics",
+	Match:   matchTilde,
+}, {
+	ID:      "slice-to-array",
+	Version: 20,
+	Desc:    "conversion from slice to array",
+	Link:    "https://go.dev/doc/go1.20#language",
+	Match:   matchSliceToArray,
+}, {
+	ID:       "fmt-errorf-multiple-wrap",
+	Version:  20,
+	Desc:     "multiple %w verbs in fmt.Errorf format",
+	Link:     "https://go.dev/doc/go1.20#errors",
+	Match:    matchErrorfMultipleWrap,
+	Semantic: true,
+}, {
+	ID:      "comparable-non-strict",
+	Version: 20,
+	Desc:    "comparable type argument that is not strictly comparable",
+	Link:    "https://go.dev/doc/go1.20#language",
+	Match:   matchComparableNonStrict,
+}, {
+	ID:      "unsafe-string-data",
+	Version: 20,
+	Desc:    "use of unsafe.String, unsafe.StringData, or unsafe.SliceData builtin",
+	Link:    "https://go.dev/doc/go1.20#language",
+	Match:   matchUnsafeBuiltin("String", "StringData", "SliceData"),
+}, {
+	ID:      "go-debug",
+	Version: 21,
+	Desc:    "//go:debug directive",
+	Link:    "https://go.dev/doc/godebug",
+	Match:   matchDirective("debug"),
+}, {
+	ID:      "go-wasmimport",
+	Version: 21,
+	Desc:    "//go:wasmimport directive",
+	Link:    "https://go.dev/doc/go1.21#wasm",
+	Match:   matchDirective("wasmimport"),
+}, {
+	ID:      "generic-inference",
+	Version: 21,
+	Desc:    "type inference added in Go 1.21",
+	Link:    "https://go.dev/doc/go1.21#language",
+	Match:   matchGenericInference,
+}, {
+	ID:      "clear",
+	Version: 21,
+	Desc:    "use of clear builtin",
+	Link:    "https://go.dev/doc/go1.21#language",
+	Match:   matchBuiltin("clear"),
+}, {
+	ID:      "max",
+	Version: 21,
+	Desc:    "use of max builtin",
+	Link:    "https://go.dev/doc/go1.21#language",
+	Match:   matchBuiltin("max"),
+	Fix:     fixMinMax,
+}, {
+	ID:      "min",
+	Version: 21,
+	Desc:    "use of min builtin",
+	Link:    "https://go.dev/doc/go1.21#language",
+	Match:   matchBuiltin("min"),
+	Fix:     fixMinMax,
+}, {
+	ID:      "range-over-int",
+	Version: 22,
+	Desc:    "range over integer",
+	Link:    "https://go.dev/doc/go1.22#language",
+	Match:   matchRangeOverInt,
+	Fix:     fixRangeOverInt,
+}, {
+	ID:       "loopvar-capture",
+	Version:  22,
+	Desc:     "loop variable captured by a closure or pointer",
+	Link:     "https://go.dev/doc/go1.22#language",
+	Match:    matchLoopVarCapture,
+	Semantic: true,
+}, {
+	ID:       "servemux-pattern",
+	Version:  22,
+	Desc:     "net/http ServeMux pattern with a method, wildcard, or {$}",
+	Link:     "https://go.dev/doc/go1.22#enhanced_routing_patterns",
+	Match:    matchServeMuxPattern,
+	Semantic: true,
+}, {
+	ID:      "range-over-func",
+	Version: 23,
+	Desc:    "range over function",
+	Link:    "https://go.dev/doc/go1.23#language",
+	Match:   matchRangeOverFunc,
+}, {
+	ID:      "cgo-noescape-nocallback",
+	Version: 24,
+	Desc:    "#cgo noescape or nocallback annotation",
+	Link:    "https://go.dev/doc/go1.24#cgo",
+	Match:   matchCgoFlag("noescape", "nocallback"),
+}, {
+	ID:      "generic-type-alias",
+	Version: 24,
+	Desc:    "generic type alias",
+	Link:    "https://go.dev/doc/go1.24#language",
+	Match:   matchGenericTypeAlias,
+}, {
+	ID:      "go-wasmexport",
+	Version: 24,
+	Desc:    "//go:wasmexport directive",
+	Link:    "https://go.dev/doc/go1.24#wasm",
+	Match:   matchDirective("wasmexport"),
+}, {
+	ID:      "new-expr",
+	Version: 26,
+	Desc:    "use of new builtin with non-type argument",
+	Link:    "https://go.dev/doc/go1.26#language",
+	Match:   matchNewExpr,
+}, {
+	ID:      "recursive-type-param",
+	Version: 26,
+	Desc:    "recursive type parameter",
+	Link:    "https://go.dev/doc/go1.26#language",
+	Match:   matchRecursiveTypeParam,
+}, {
+	ID:      "embedded-field-key",
+	Version: 27,
+	Desc:    "embedded struct field in composite literal",
+	Link:    "https://go.dev/doc/go1.27#language",
+	Match:   matchEmbeddedFieldKey,
+}, {
+	ID:      "generic-method",
+	Version: 27,
+	Desc:    "generic method",
+	Link:    "https://go.dev/doc/go1.27#language",
+	Match:   matchGenericMethod,
+}}
+
+func init() {
+	for _, r := range langRules {
+		Register(r)
+	}
+}
+
+func matchMissingFinalReturn(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	var body *ast.BlockStmt
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		body = node.Body
+	case *ast.FuncLit:
+		body = node.Body
+	}
+	if body == nil || len(body.List) == 0 {
+		return token.NoPos, false
+	}
+	last := body.List[len(body.List)-1]
+	if _, ok := last.(*ast.ReturnStmt); ok {
+		return token.NoPos, false
+	}
+	return last.End(), true
+}
+
+func matchMethodValue(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, ok := node.(*ast.SelectorExpr)
+	if !ok {
+		return token.NoPos, false
+	}
+	sel, ok := c.Info.Selections[expr]
+	if !ok || sel.Kind() != types.MethodVal {
+		return token.NoPos, false
+	}
+	if call, ok := c.Parent().(*ast.CallExpr); ok && call.Fun == expr {
+		return token.NoPos, false
+	}
+	return expr.Pos(), true
+}
+
+func matchRangeWithoutVars(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	stmt, ok := node.(*ast.RangeStmt)
+	if !ok || stmt.Key != nil || stmt.Value != nil {
+		return token.NoPos, false
+	}
+	return stmt.Pos(), true
+}
+
+func matchElidedCompositeKeyType(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	lit, ok := node.(*ast.CompositeLit)
+	if !ok {
+		return token.NoPos, false
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if ck, ok := kv.Key.(*ast.CompositeLit); ok && ck.Type == nil {
+			return ck.Pos(), true
+		}
+	}
+	return token.NoPos, false
+}
+
+func matchThreeIndexSlice(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, ok := node.(*ast.SliceExpr)
+	if !ok || !expr.Slice3 {
+		return token.NoPos, false
+	}
+	return expr.Pos(), true
+}
+
+// Function conversionTypes returns the underlying types of the target and argument
+// when node is a type conversion.
+func conversionTypes(c *RuleContext, node ast.Node) (funtyp, argtyp types.Type, ok bool) {
+	expr, ok := node.(*ast.CallExpr)
+	if !ok || len(expr.Args) != 1 {
+		return nil, nil, false
+	}
+	funtv, ok := c.Info.Types[expr.Fun]
+	if !ok || !funtv.IsType() {
+		return nil, nil, false
+	}
+	argtv, ok := c.Info.Types[expr.Args[0]]
+	if !ok || argtv.Type == nil {
+		return nil, nil, false
+	}
+	return funtv.Type.Underlying(), argtv.Type.Underlying(), true
+}
+
+func matchStructTagConversion(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	funtyp, argtyp, ok := conversionTypes(c, node)
+	if !ok {
+		return token.NoPos, false
+	}
+	argStruct, ok := argtyp.(*types.Struct)
+	if !ok {
+		return token.NoPos, false
+	}
+	funStruct, ok := funtyp.(*types.Struct)
+	if !ok {
+		return token.NoPos, false
+	}
+	return node.Pos(), differingTags(argStruct, funStruct)
+}
+
+func matchSliceToArray(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	funtyp, argtyp, ok := conversionTypes(c, node)
+	if !ok {
+		return token.NoPos, false
+	}
+	if _, ok := argtyp.(*types.Slice); !ok {
+		return token.NoPos, false
+	}
+	_, ok = funtyp.(*types.Array)
+	return node.Pos(), ok
+}
+
+func matchSliceToArrayPointer(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	funtyp, argtyp, ok := conversionTypes(c, node)
+	if !ok {
+		return token.NoPos, false
+	}
+	if _, ok := argtyp.(*types.Slice); !ok {
+		return token.NoPos, false
+	}
+	ptr, ok := funtyp.(*types.Pointer)
+	if !ok {
+		return token.NoPos, false
+	}
+	_, ok = ptr.Elem().Underlying().(*types.Array)
+	return node.Pos(), ok
+}
+
+func matchTypeAlias(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	spec, ok := node.(*ast.TypeSpec)
+	if !ok || !spec.Assign.IsValid() || isGenericTypeSpec(spec) {
+		return token.NoPos, false
+	}
+	return spec.Pos(), true
+}
+
+func matchGenericTypeAlias(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	spec, ok := node.(*ast.TypeSpec)
+	if !ok || !spec.Assign.IsValid() || !isGenericTypeSpec(spec) {
+		return token.NoPos, false
+	}
+	return spec.Pos(), true
+}
+
+func isGenericTypeSpec(spec *ast.TypeSpec) bool {
+	return spec.TypeParams != nil && len(spec.TypeParams.List) > 0
+}
+
+// Go 1.13 added underscores in numeric literals,
+// the 0b and 0o prefixes,
+// hexadecimal floating-point literals,
+// and non-decimal imaginary literals.
+// Hexadecimal integers and decimal imaginary literals like 2i were legal all along.
+func matchExpandedNumericLiteral(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	lit, ok := node.(*ast.BasicLit)
+	if !ok {
+		return token.NoPos, false
+	}
+	switch lit.Kind {
+	case token.INT, token.FLOAT, token.IMAG:
+	default:
+		return token.NoPos, false
+	}
+
+	if strings.Contains(lit.Value, "_") {
+		return lit.Pos(), true
+	}
+	var prefix string
+	if len(lit.Value) > 1 && lit.Value[0] == '0' {
+		prefix = strings.ToLower(lit.Value[:2])
+	}
+	switch prefix {
+	case "0b", "0o":
+		return lit.Pos(), true
+	case "0x":
+		// A hexadecimal float (with a p exponent) or imaginary literal.
+		return lit.Pos(), lit.Kind != token.INT
+	}
+
+	return token.NoPos, false
+}
+
+func matchSignedShiftCount(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	switch node := node.(type) {
+	case *ast.AssignStmt:
+		switch node.Tok {
+		case token.SHL_ASSIGN, token.SHR_ASSIGN:
+			return node.Pos(), len(node.Rhs) == 1 && c.isSigned(node.Rhs[0])
+		}
+	case *ast.BinaryExpr:
+		switch node.Op {
+		case token.SHL, token.SHR:
+			return node.Pos(), c.isSigned(node.Y)
+		}
+	}
+	return token.NoPos, false
+}
+
+// Is this interface defined in terms of overlapping method sets?
+// If so, require Go 1.14 or later.
+func matchOverlappingInterfaces(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, ok := node.(*ast.InterfaceType)
+	if !ok {
+		return token.NoPos, false
+	}
+	tv, ok := c.Info.Types[expr]
+	if !ok {
+		return token.NoPos, false
+	}
+	intf, ok := tv.Type.(*types.Interface)
+	if !ok {
+		return token.NoPos, false
+	}
+
+	for i := 0; i < intf.NumEmbeddeds(); i++ {
+		embed := intf.EmbeddedType(i)
+		if embed1, ok := embed.Underlying().(*types.Interface); ok {
+			for j := i + 1; j < intf.NumEmbeddeds(); j++ {
+				embed = intf.EmbeddedType(j)
+				if embed2, ok := embed.Underlying().(*types.Interface); ok {
+					for ii := 0; ii < embed1.NumMethods(); ii++ {
+						for jj := 0; jj < embed2.NumMethods(); jj++ {
+							if embed1.Method(ii).Name() == embed2.Method(jj).Name() { // we don't care whether the signatures match
+								return expr.Pos(), true
+							}
+						}
+					}
+				}
+			}
+
+			for j := 0; j < intf.NumExplicitMethods(); j++ {
+				for ii := 0; ii < embed1.NumMethods(); ii++ {
+					if intf.ExplicitMethod(j).Name() == embed1.Method(ii).Name() { // we don't care whether the signatures match
+						return expr.Pos(), true
+					}
+				}
+			}
+		}
+	}
+
+	return token.NoPos, false
+}
+
+func matchInterfaceTypeTerms(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, ok := node.(*ast.InterfaceType)
+	if !ok {
+		return token.NoPos, false
+	}
+	tv, ok := c.Info.Types[expr]
+	if !ok {
+		return token.NoPos, false
+	}
+	intf, ok := tv.Type.(*types.Interface)
+	if !ok {
+		return token.NoPos, false
+	}
+	return expr.Pos(), !intf.IsMethodSet()
+}
+
+// Function builtinCall returns the name of the builtin function called by node, if any.
+func builtinCall(c *RuleContext, node ast.Node) (*ast.CallExpr, string) {
+	expr, ok := node.(*ast.CallExpr)
+	if !ok {
+		return nil, ""
+	}
+	tv, ok := c.Info.Types[expr.Fun]
+	if !ok || !tv.IsBuiltin() {
+		return nil, ""
+	}
+	switch fun := ast.Unparen(expr.Fun).(type) {
+	case *ast.Ident:
+		return expr, fun.Name
+	case *ast.SelectorExpr:
+		if pkgname, ok := c.Info.Uses[getID(fun.X)].(*types.PkgName); ok && pkgname.Imported().Path() == "unsafe" {
+			return expr, "unsafe." + fun.Sel.Name
+		}
+	}
+	return nil, ""
+}
+
+func matchBuiltin(name string) func(*RuleContext, ast.Node) (token.Pos, bool) {
+	return func(c *RuleContext, node ast.Node) (token.Pos, bool) {
+		expr, got := builtinCall(c, node)
+		if got != name {
+			return token.NoPos, false
+		}
+		return expr.Pos(), true
+	}
+}
+
+func matchUnsafeBuiltin(names ...string) func(*RuleContext, ast.Node) (token.Pos, bool) {
+	return func(c *RuleContext, node ast.Node) (token.Pos, bool) {
+		expr, got := builtinCall(c, node)
+		for _, name := range names {
+			if got == "unsafe."+name {
+				return expr.Pos(), true
+			}
+		}
+		return token.NoPos, false
+	}
+}
+
+func matchNewExpr(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, name := builtinCall(c, node)
+	if name != "new" || len(expr.Args) != 1 {
+		return token.NoPos, false
+	}
+	tv, ok := c.Info.Types[expr.Args[0]]
+	if !ok || tv.IsType() {
+		return token.NoPos, false
+	}
+	return expr.Pos(), true
+}
+
+func matchAny(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	ident, ok := node.(*ast.Ident)
+	if !ok || ident.Name != "any" {
+		return token.NoPos, false
+	}
+	if tv, ok := c.Info.Types[ident]; !ok || !tv.IsType() {
+		return token.NoPos, false
+	}
+
+	// It's a type named "any," but is it the predefined "any" type?
+	obj, ok := c.Info.Uses[ident]
+	if !ok || obj.Pkg() != nil {
+		return token.NoPos, false
+	}
+	return ident.Pos(), true
+}
+
+func matchGenericFuncDecl(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	decl, ok := node.(*ast.FuncDecl)
+	if !ok || decl.Type.TypeParams == nil || len(decl.Type.TypeParams.List) == 0 {
+		return token.NoPos, false
+	}
+	return decl.Pos(), true
+}
+
+func matchGenericMethod(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	decl, ok := node.(*ast.FuncDecl)
+	if !ok || decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return token.NoPos, false
+	}
+	return matchGenericFuncDecl(c, node)
+}
+
+func matchGenericFuncLit(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	// I think this case is impossible.
+	lit, ok := node.(*ast.FuncLit)
+	if !ok || lit.Type.TypeParams == nil || len(lit.Type.TypeParams.List) == 0 {
+		return token.NoPos, false
+	}
+	return lit.Pos(), true
+}
+
+func matchGenericFuncType(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, ok := node.(*ast.FuncType)
+	if !ok || expr.TypeParams == nil || len(expr.TypeParams.List) == 0 {
+		return token.NoPos, false
+	}
+	switch c.Parent().(type) {
+	case *ast.FuncDecl, *ast.FuncLit:
+		// Reported by generic-func-decl and generic-func-lit.
+		return token.NoPos, false
+	}
+	return expr.Pos(), true
+}
+
+func matchGenericInstantiation(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	switch expr := node.(type) {
+	case *ast.Ident:
+		// An instantiation with inferred type arguments.
+		if _, ok := c.Info.Instances[expr]; !ok {
+			return token.NoPos, false
+		}
+		var (
+			child  ast.Node = expr
+			parent          = c.Parent()
+		)
+		if sel, ok := parent.(*ast.SelectorExpr); ok && sel.Sel == expr && len(c.stack) > 1 {
+			child, parent = sel, c.stack[len(c.stack)-2]
+		}
+		switch parent := parent.(type) {
+		case *ast.IndexExpr:
+			if parent.X == child {
+				return token.NoPos, false // explicit, reported below
+			}
+		case *ast.IndexListExpr:
+			if parent.X == child {
+				return token.NoPos, false // explicit, reported below
+			}
+		}
+		return expr.Pos(), true
+
+	case *ast.IndexExpr:
+		return expr.Pos(), c.isTypeExpr(expr.Index)
+	case *ast.IndexListExpr:
+		for _, index := range expr.Indices {
+			if c.isTypeExpr(index) {
+				return expr.Pos(), true
+			}
+		}
+	}
+	return token.NoPos, false
+}
+
+// Before Go 1.20, only strictly comparable types
+// (not interfaces, nor structs or arrays containing them)
+// satisfied the comparable constraint.
+// This checks explicit and inferred instantiations alike.
+func matchComparableNonStrict(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	ident, ok := node.(*ast.Ident)
+	if !ok {
+		return token.NoPos, false
+	}
+	inst, ok := c.Info.Instances[ident]
+	if !ok {
+		return token.NoPos, false
+	}
+	obj := c.Info.Uses[ident]
+	if obj == nil {
+		return token.NoPos, false
+	}
+
+	var tparams *types.TypeParamList
+	switch typ := obj.Type().(type) {
+	case *types.Named:
+		tparams = typ.Origin().TypeParams()
+	case *types.Alias:
+		tparams = typ.Origin().TypeParams()
+	case *types.Signature:
+		tparams = typ.TypeParams()
+	}
+
+	for i := 0; i < tparams.Len() && i < inst.TypeArgs.Len(); i++ {
+		constraint, ok := tparams.At(i).Constraint().Underlying().(*types.Interface)
+		if !ok || !constraint.IsComparable() {
+			continue
+		}
+		if !strictlyComparable(inst.TypeArgs.At(i)) {
+			return ident.Pos(), true
+		}
+	}
+	return token.NoPos, false
+}
+
+// Function strictlyComparable tells whether typ is strictly comparable,
+// as defined in the spec:
+// comparable, and neither an interface nor composed of interfaces.
+// Type parameters count as strictly comparable,
+// since before Go 1.20 only those constrained by comparable could be compared at all.
+func strictlyComparable(typ types.Type) bool {
+	if _, ok := types.Unalias(typ).(*types.TypeParam); ok {
+		return true
+	}
+	switch typ := typ.Underlying().(type) {
+	case *types.Interface:
+		return false
+	case *types.Struct:
+		for f := range typ.Fields() {
+			if !strictlyComparable(f.Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Array:
+		return strictlyComparable(typ.Elem())
+	}
+	return types.Comparable(typ)
+}
+
+func matchGenericTypeDecl(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	spec, ok := node.(*ast.TypeSpec)
+	if !ok || !isGenericTypeSpec(spec) {
+		return token.NoPos, false
+	}
+	return spec.Pos(), true
+}
+
+func matchTilde(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	expr, ok := node.(*ast.UnaryExpr)
+	if !ok || expr.Op != token.TILDE {
+		return token.NoPos, false
+	}
+	return expr.Pos(), true
+}
+
+// Function rangeType returns the underlying type of the range expression when node is a range statement.
+func rangeType(c *RuleContext, node ast.Node) (*ast.RangeStmt, types.Type) {
+	stmt, ok := node.(*ast.RangeStmt)
+	if !ok {
+		return nil, nil
+	}
+	tv, ok := c.Info.Types[stmt.X]
+	if !ok || tv.Type == nil {
+		return nil, nil
+	}
+	return stmt, tv.Type.Underlying()
+}
+
+func matchRangeOverInt(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	stmt, typ := rangeType(c, node)
+	// Any integer type will do, including uintptr and untyped constants like 'x'.
+	basic, ok := typ.(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 {
+		return token.NoPos, false
+	}
+	return stmt.Pos(), true
+}
+
+func matchRangeOverFunc(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	stmt, typ := rangeType(c, node)
+	if _, ok := typ.(*types.Signature); !ok {
+		return token.NoPos, false
+	}
+	return stmt.Pos(), true
+}
+
+// Before Go 1.22, the variables declared by a "for" statement
+// were shared by all iterations of the loop.
+// Code that lets one outlive an iteration
+// (in a closure, or by taking its address,
+// perhaps implicitly with a pointer-receiver method)
+// compiles either way but behaves differently.
+// This matches only the captures that certainly outlive the iteration:
+// those in go and defer statements,
+// and those stored in variables declared outside the loop body
+// or sent on channels.
+// Other captures, like closures passed to sort.Slice or t.Run,
+// usually complete within the iteration;
+// see [possibleLoopVarCapture].
+func matchLoopVarCapture(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	v := newLoopVarVisitor(c, node)
+	if v == nil {
+		return token.NoPos, false
+	}
+	ast.Walk(v, v.body)
+	return v.pos, v.pos.IsValid()
+}
+
+// Function possibleLoopVarCapture tells whether node is a loop
+// with a captured loop variable that [matchLoopVarCapture] cannot show outlives the iteration.
+// Such captures are reported as warnings above a target
+// but do not count toward the minimum version.
+func possibleLoopVarCapture(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	v := newLoopVarVisitor(c, node)
+	if v == nil {
+		return token.NoPos, false
+	}
+	ast.Walk(v, v.body)
+	if v.pos.IsValid() {
+		return token.NoPos, false
+	}
+	return v.maybe, v.maybe.IsValid()
+}
+
+// Method possibleLoopVarCaptures adds the [possibleLoopVarCapture]s in file
+// that are above the target to the findings.
+func (p *pkgScanner) possibleLoopVarCaptures(file *ast.File) {
+	i := slices.IndexFunc(p.langRules, func(r Rule) bool { return r.ID == "loopvar-capture" })
+	if i < 0 {
+		return
+	}
+	r := p.langRules[i]
+	if r.Version <= max(p.target, p.guard) {
+		return
+	}
+	c := &RuleContext{Fset: p.fset, Info: p.info, file: file}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if pos, ok := possibleLoopVarCapture(c, node); ok {
+			p.findings = append(p.findings, posResult{
+				version:  r.Version,
+				pos:      p.fset.Position(pos),
+				at:       pos,
+				desc:     "loop variable possibly captured beyond its iteration",
+				rule:     r.ID,
+				semantic: true,
+			})
+		}
+		return true
+	})
+}
+
+func newLoopVarVisitor(c *RuleContext, node ast.Node) *loopVarVisitor {
+	var (
+		idents []*ast.Ident
+		body   *ast.BlockStmt
+	)
+	switch node := node.(type) {
+	case *ast.ForStmt:
+		if init, ok := node.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+			for _, lhs := range init.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					idents = append(idents, ident)
+				}
+			}
+		}
+		body = node.Body
+	case *ast.RangeStmt:
+		if node.Tok == token.DEFINE {
+			for _, expr := range []ast.Expr{node.Key, node.Value} {
+				if ident, ok := expr.(*ast.Ident); ok {
+					idents = append(idents, ident)
+				}
+			}
+		}
+		body = node.Body
+	}
+
+	v := &loopVarVisitor{
+		c:         c,
+		vars:      make(map[types.Object]bool),
+		body:      body,
+		called:    make(map[ast.Expr]bool),
+		immediate: make(map[*ast.FuncLit]bool),
+	}
+	for _, ident := range idents {
+		if obj := c.Info.Defs[ident]; obj != nil {
+			v.vars[obj] = true
+		}
+	}
+	if len(v.vars) == 0 || body == nil {
+		return nil
+	}
+	return v
+}
+
+type loopVarVisitor struct {
+	c     *RuleContext
+	vars  map[types.Object]bool // the loop variables
+	body  *ast.BlockStmt        // the loop body
+	pos   token.Pos             // where one certainly outlives the iteration, once found
+	maybe token.Pos             // where one is first captured in a way that may outlive the iteration
+
+	// The function expressions of calls that complete within the iteration,
+	// i.e. not in a go or defer statement.
+	called map[ast.Expr]bool
+
+	// Function literals that are called on the spot.
+	immediate map[*ast.FuncLit]bool
+}
+
+func (v *loopVarVisitor) Visit(node ast.Node) ast.Visitor {
+	if v.pos.IsValid() {
+		return nil
+	}
+
+	switch node := node.(type) {
+	case *ast.GoStmt:
+		v.deferred(node.Call)
+		return v
+
+	case *ast.DeferStmt:
+		v.deferred(node.Call)
+		return v
+
+	case *ast.AssignStmt:
+		if node.Tok == token.DEFINE {
+			break
+		}
+		for i, lhs := range node.Lhs {
+			if !v.outside(lhs) {
+				continue
+			}
+			rhs := node.Rhs
+			if len(node.Rhs) == len(node.Lhs) {
+				rhs = node.Rhs[i : i+1]
+			}
+			for _, expr := range rhs {
+				if pos := v.holds(expr); pos.IsValid() {
+					v.pos = pos
+					return nil
+				}
+			}
+		}
+
+	case *ast.SendStmt:
+		if pos := v.holds(node.Value); pos.IsValid() {
+			v.pos = pos
+			return nil
+		}
+
+	case *ast.CallExpr:
+		fun := ast.Unparen(node.Fun)
+		if _, ok := v.called[fun]; !ok {
+			v.called[fun] = true
+			if lit, ok := fun.(*ast.FuncLit); ok {
+				v.immediate[lit] = true
+			}
+		}
+
+	case *ast.FuncLit:
+		if v.immediate[node] {
+			return v
+		}
+		v.capture(v.funcLitUse(node))
+		return nil
+
+	case *ast.UnaryExpr:
+		if node.Op == token.AND {
+			if ident := v.addressed(node.X); ident != nil {
+				v.capture(ident.Pos())
+				return nil
+			}
+		}
+
+	case *ast.SelectorExpr:
+		// A method value that outlives its call may outlive the iteration.
+		if v.called[node] {
+			break
+		}
+		if ident := v.pointerMethod(node); ident != nil {
+			v.capture(ident.Pos())
+			return nil
+		}
+	}
+
+	return v
+}
+
+// Method capture records a capture that may outlive the iteration.
+func (v *loopVarVisitor) capture(pos token.Pos) {
+	if pos.IsValid() && !v.maybe.IsValid() {
+		v.maybe = pos
+	}
+}
+
+// Method deferred notes that call, in a go or defer statement,
+// runs after the current iteration is done,
+// so any capture in it outlives the iteration.
+func (v *loopVarVisitor) deferred(call *ast.CallExpr) {
+	fun := ast.Unparen(call.Fun)
+	v.called[fun] = false
+
+	switch fun := fun.(type) {
+	case *ast.FuncLit:
+		v.pos = v.funcLitUse(fun)
+	case *ast.SelectorExpr:
+		// A pointer-receiver method on a variable takes its address.
+		if ident := v.pointerMethod(fun); ident != nil {
+			v.pos = ident.Pos()
+		}
+	}
+	for _, arg := range call.Args {
+		if v.pos.IsValid() {
+			return
+		}
+		v.pos = v.holds(arg)
+	}
+}
+
+// Method holds returns the position of a captured loop variable
+// that the value of expr holds onto,
+// or token.NoPos if there is none.
+func (v *loopVarVisitor) holds(expr ast.Expr) token.Pos {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.FuncLit:
+		return v.funcLitUse(expr)
+	case *ast.UnaryExpr:
+		if expr.Op == token.AND {
+			if ident := v.addressed(expr.X); ident != nil {
+				return ident.Pos()
+			}
+		}
+	case *ast.SelectorExpr:
+		if ident := v.pointerMethod(expr); ident != nil {
+			return ident.Pos()
+		}
+	case *ast.CompositeLit:
+		for _, elt := range expr.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			if pos := v.holds(elt); pos.IsValid() {
+				return pos
+			}
+		}
+	case *ast.CallExpr:
+		if id, ok := ast.Unparen(expr.Fun).(*ast.Ident); ok {
+			if _, ok := v.c.Info.Uses[id].(*types.Builtin); ok && id.Name == "append" {
+				for _, arg := range expr.Args {
+					if pos := v.holds(arg); pos.IsValid() {
+						return pos
+					}
+				}
+			}
+		}
+	}
+	return token.NoPos
+}
+
+// Method outside tells whether assigning to lhs stores a value
+// somewhere that survives the iteration:
+// anything but a variable declared in the loop body
+// (or a field or array element of one).
+func (v *loopVarVisitor) outside(lhs ast.Expr) bool {
+	for {
+		switch e := ast.Unparen(lhs).(type) {
+		case *ast.Ident:
+			if e.Name == "_" {
+				return false
+			}
+			obj := v.c.Info.Uses[e]
+			if obj == nil {
+				obj = v.c.Info.Defs[e]
+			}
+			return obj == nil || obj.Pos() < v.body.Pos() || obj.Pos() >= v.body.End()
+		case *ast.SelectorExpr:
+			if sel, ok := v.c.Info.Selections[e]; !ok || sel.Indirect() {
+				return true
+			}
+			lhs = e.X
+		case *ast.IndexExpr:
+			tv, ok := v.c.Info.Types[e.X]
+			if !ok || tv.Type == nil {
+				return true
+			}
+			if _, ok := tv.Type.Underlying().(*types.Array); !ok {
+				return true
+			}
+			lhs = e.X
+		default:
+			return true
+		}
+	}
+}
+
+// Method funcLitUse returns the position of the first use of a loop variable in lit,
+// or token.NoPos if there is none.
+func (v *loopVarVisitor) funcLitUse(lit *ast.FuncLit) token.Pos {
+	var pos token.Pos
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if pos.IsValid() {
+			return false
+		}
+		if ident, ok := n.(*ast.Ident); ok && v.vars[v.c.Info.Uses[ident]] {
+			pos = ident.Pos()
+		}
+		return true
+	})
+	return pos
+}
+
+// Method pointerMethod returns the loop variable whose address is taken
+// by selecting a pointer-receiver method in sel,
+// or nil if there is none.
+func (v *loopVarVisitor) pointerMethod(sel *ast.SelectorExpr) *ast.Ident {
+	s, ok := v.c.Info.Selections[sel]
+	if !ok || s.Kind() != types.MethodVal || s.Indirect() {
+		return nil
+	}
+	sig, ok := s.Obj().Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return nil
+	}
+	if _, ok := sig.Recv().Type().(*types.Pointer); !ok {
+		return nil
+	}
+	return v.addressed(sel.X)
+}
+
+// Method addressed returns the loop variable whose address is taken
+// when the address of expr is,
+// or nil if there is none.
+func (v *loopVarVisitor) addressed(expr ast.Expr) *ast.Ident {
+	switch expr := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		if v.vars[v.c.Info.Uses[expr]] {
+			return expr
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := v.c.Info.Selections[expr]; ok && sel.Kind() == types.FieldVal && !sel.Indirect() {
+			return v.addressed(expr.X)
+		}
+	case *ast.IndexExpr:
+		if tv, ok := v.c.Info.Types[expr.X]; ok && tv.Type != nil {
+			if _, ok := tv.Type.Underlying().(*types.Array); ok {
+				return v.addressed(expr.X)
+			}
+		}
+	}
+	return nil
+}
+
+func matchRecursiveTypeParam(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+	spec, ok := node.(*ast.TypeSpec)
+	if !ok || !isGenericTypeSpec(spec) {
+		return token.NoPos, false
+	}
+	v := recursiveTypeParamVisitor{name: spec.Name}
+	ast.Walk(&v, spec.TypeParams)
+	return spec.Pos(), v.found
+}
+
+type recursiveTypeParamVisitor struct {
+	name  *ast.Ident
+	found bool
+}
+
+func (v *recursiveTypeParamVisitor) Visit(node ast.Node) ast.Visitor {
+	if v.found {
+		return nil
+	}
+	if ident, ok := node.(*ast.Ident); ok && ident.Name == v.name.Name {
+		v.found = true
+		return nil
+	}
+	return v
+}
+
+func matchEmbeddedFieldKey(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	lit, ok := node.(*ast.CompositeLit)
+	if !ok {
+		return token.NoPos, false
+	}
+	tv, ok := c.Info.Types[lit]
+	if !ok || tv.Type == nil {
+		return token.NoPos, false
+	}
+	structType, ok := tv.Type.Underlying().(*types.Struct)
+	if !ok {
+		return token.NoPos, false
+	}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		ident, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		var isDirect bool
+		for f := range structType.Fields() {
+			if f.Name() == ident.Name {
+				isDirect = true
+				break
+			}
+		}
+
+		if !isDirect && isEmbeddedField(structType, ident.Name) {
+			return ident.Pos(), true
+		}
+	}
+
+	return token.NoPos, false
+}
+
+func differingTags(a, b *types.Struct) bool {
+	n := a.NumFields()
+	if n != b.NumFields() {
+		return false // sic - we don't care if the fields differ, only whether the tags differ
+	}
+	for i := range n {
+		if a.Tag(i) != b.Tag(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmbeddedField(str *types.Struct, name string) bool {
+	for f := range str.Fields() {
+		if !f.Anonymous() {
+			continue
+		}
+		typ := f.Type().Underlying()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem().Underlying()
+		}
+		embeddedStruct, ok := typ.(*types.Struct)
+		if !ok {
+			continue
+		}
+		for f2 := range embeddedStruct.Fields() {
+			if f2.Name() == name {
+				return true
+			}
+		}
+		if isEmbeddedField(embeddedStruct, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,746 @@
+package mingo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/bobg/errors"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// LangServer is a small Language Server Protocol server for mingo.
+//
+// It publishes a diagnostic for each use of a language feature or stdlib identifier
+// above the version in go.mod
+// (or above Target),
+// rechecking each package as its files change.
+// Hovering over a stdlib identifier shows the Go version that added it,
+// and there are code actions that raise the go directive in go.mod
+// to the version a diagnostic needs.
+type LangServer struct {
+	Target  int    // If nonzero, report findings above Go 1.Target instead of above the go.mod version.
+	HistDir string // Find Go stdlib history in this directory (default: $GOROOT/api).
+	Tests   bool   // Include *_test.go files.
+	Verbose bool   // Log to stderr.
+}
+
+// Serve runs the server on the given streams
+// (usually stdin and stdout)
+// until the client sends the exit notification or closes the input.
+func (ls LangServer) Serve(r io.Reader, w io.Writer) error {
+	sess := &lspSession{
+		ls:       ls,
+		s:        &Scanner{HistDir: ls.HistDir, Tests: ls.Tests},
+		w:        w,
+		overlay:  make(map[string][]byte),
+		pkgs:     make(map[string]*packages.Package),
+		findings: make(map[string][]lspFinding),
+	}
+	if err := sess.s.ensureHistory(); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		msg, err := readLSPMessage(br)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := sess.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+type lspSession struct {
+	ls LangServer
+	s  *Scanner
+	w  io.Writer
+
+	overlay  map[string][]byte            // contents of open files, by filename
+	pkgs     map[string]*packages.Package // the latest package containing each open file, by filename
+	findings map[string][]lspFinding      // by filename
+}
+
+type lspFinding struct {
+	posResult
+	diag  lspDiagnostic
+	gomod string // the go.mod file of the finding's module
+}
+
+// JSON-RPC and LSP types,
+// with just the fields that mingo uses.
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type lspResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  any              `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   lspError         `json:"error"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // in UTF-16 code units
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocument struct {
+	URI     string `json:"uri"`
+	Text    string `json:"text,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+type lspDiagnostic struct {
+	Range           lspRange            `json:"range"`
+	Severity        int                 `json:"severity"`
+	Code            string              `json:"code,omitempty"`
+	CodeDescription *lspCodeDescription `json:"codeDescription,omitempty"`
+	Source          string              `json:"source"`
+	Message         string              `json:"message"`
+}
+
+type lspCodeDescription struct {
+	Href string `json:"href"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind"`
+	Diagnostics []lspDiagnostic `json:"diagnostics,omitempty"`
+	Edit        struct {
+		Changes map[string][]lspTextEdit `json:"changes"`
+	} `json:"edit"`
+}
+
+const (
+	lspMethodNotFound = -32601
+	lspInternalError  = -32603
+
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+)
+
+func readLSPMessage(r *bufio.Reader) (*lspMessage, error) {
+	body, err := readLSPFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	msg := new(lspMessage)
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, errors.Wrap(err, "decoding message")
+	}
+	return msg, nil
+}
+
+// Function readLSPFrame reads the body of the next message,
+// which is preceded by headers including Content-Length.
+func readLSPFrame(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, val, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(val)); err != nil {
+				return nil, errors.Wrapf(err, "parsing Content-Length %q", val)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, errors.Wrap(err, "reading message body")
+	}
+	return body, nil
+}
+
+func (sess *lspSession) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(sess.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (sess *lspSession) handle(msg *lspMessage) error {
+	result, err := sess.dispatch(msg)
+	if msg.ID == nil {
+		// A notification: there is nowhere to send errors but the log.
+		if err != nil {
+			sess.logf("%s: %s", msg.Method, err)
+		}
+		return nil
+	}
+	if errors.Is(err, errLSPMethodNotFound) {
+		return sess.write(lspErrorResponse{JSONRPC: "2.0", ID: msg.ID, Error: lspError{Code: lspMethodNotFound, Message: err.Error()}})
+	}
+	if err != nil {
+		return sess.write(lspErrorResponse{JSONRPC: "2.0", ID: msg.ID, Error: lspError{Code: lspInternalError, Message: err.Error()}})
+	}
+	return sess.write(lspResponse{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+var errLSPMethodNotFound = errors.New("method not found")
+
+func (sess *lspSession) dispatch(msg *lspMessage) (any, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // full text
+					"save":      true,
+				},
+				"hoverProvider":      true,
+				"codeActionProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "mingo"},
+		}, nil
+
+	case "initialized", "$/setTrace", "$/cancelRequest", "workspace/didChangeConfiguration":
+		return nil, nil
+
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, sess.update(params.TextDocument.URI, []byte(params.TextDocument.Text))
+
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return nil, sess.update(params.TextDocument.URI, []byte(text))
+
+	case "textDocument/didSave":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriFilename(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := sess.overlay[filename]; ok {
+			return nil, nil // already up to date
+		}
+		return nil, sess.check(filename)
+
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriFilename(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		delete(sess.overlay, filename)
+		delete(sess.pkgs, filename)
+		delete(sess.findings, filename)
+		return nil, sess.publish(filename, nil)
+
+	case "textDocument/hover":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Position     lspPosition     `json:"position"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return sess.hover(params.TextDocument.URI, params.Position)
+
+	case "textDocument/codeAction":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Range        lspRange        `json:"range"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return sess.codeActions(params.TextDocument.URI, params.Range)
+	}
+
+	if strings.HasPrefix(msg.Method, "$/") || msg.ID == nil {
+		return nil, nil
+	}
+	return nil, errors.Wrapf(errLSPMethodNotFound, "%s", msg.Method)
+}
+
+// Method update records the new contents of an open file and rechecks.
+// A change to a go.mod file rechecks every open Go file.
+func (sess *lspSession) update(uri string, text []byte) error {
+	filename, err := uriFilename(uri)
+	if err != nil {
+		return err
+	}
+	sess.overlay[filename] = text
+
+	if filepath.Base(filename) != "go.mod" {
+		return sess.check(filename)
+	}
+	for other := range sess.pkgs {
+		if err := sess.check(other); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method check loads and scans the package containing filename,
+// and publishes diagnostics for each of its files.
+func (sess *lspSession) check(filename string) error {
+	if !strings.HasSuffix(filename, ".go") {
+		return nil
+	}
+
+	conf := &packages.Config{
+		Mode:    Mode,
+		Dir:     filepath.Dir(filename),
+		Tests:   sess.ls.Tests,
+		Overlay: sess.overlay,
+	}
+	pkgs, err := packages.Load(conf, ".")
+	if err != nil {
+		return errors.Wrapf(err, "loading package for %s", filename)
+	}
+
+	var (
+		findings = make(map[string][]lspFinding)
+		seen     = make(map[token.Position]bool) // packages with tests share files
+	)
+	for _, pkg := range pkgs {
+		if pkg.Module == nil || pkg.TypesInfo == nil {
+			continue
+		}
+		target := sess.ls.Target
+		if target == 0 && pkg.Module.GoVersion != "" {
+			if target, err = parseGoVersion(pkg.Module.GoVersion); err != nil {
+				return errors.Wrapf(err, "in %s", pkg.Module.GoMod)
+			}
+		}
+		if target == 0 {
+			continue
+		}
+
+		for _, file := range pkg.Syntax {
+			name := pkg.Fset.Position(file.Pos()).Filename
+			if _, ok := sess.pkgs[name]; ok || name == filename {
+				sess.pkgs[name] = pkg
+			}
+			if _, ok := findings[name]; !ok {
+				findings[name] = nil
+			}
+		}
+
+		p := sess.s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+		p.report = true
+		p.target = target
+		if err := p.files(pkg.Syntax); err != nil {
+			// Probably a file in mid-edit.
+			sess.logf("scanning %s: %s", pkg.PkgPath, err)
+			continue
+		}
+
+		for _, f := range p.findings {
+			if seen[f.pos] {
+				continue
+			}
+			seen[f.pos] = true
+
+			d := p.diagnostic(f)
+			diag := lspDiagnostic{
+				Range:    sess.identRange(f.pos),
+				Severity: lspSeverityWarning,
+				Code:     d.Category,
+				Source:   "mingo",
+				Message:  d.Message,
+			}
+			if f.provisional {
+				diag.Severity = lspSeverityInformation
+			}
+			if d.URL != "" {
+				diag.CodeDescription = &lspCodeDescription{Href: d.URL}
+			}
+			findings[f.pos.Filename] = append(findings[f.pos.Filename], lspFinding{
+				posResult: f,
+				diag:      diag,
+				gomod:     pkg.Module.GoMod,
+			})
+		}
+	}
+
+	for name, ff := range findings {
+		sess.findings[name] = ff
+		if err := sess.publish(name, ff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sess *lspSession) publish(filename string, findings []lspFinding) error {
+	diags := []lspDiagnostic{}
+	for _, f := range findings {
+		diags = append(diags, f.diag)
+	}
+	return sess.write(lspNotification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: map[string]any{
+			"uri":         filenameURI(filename),
+			"diagnostics": diags,
+		},
+	})
+}
+
+// Method hover tells which Go version added the stdlib identifier at pos, if any.
+func (sess *lspSession) hover(uri string, pos lspPosition) (any, error) {
+	filename, err := uriFilename(uri)
+	if err != nil {
+		return nil, err
+	}
+	pkg, ok := sess.pkgs[filename]
+	if !ok {
+		if err := sess.check(filename); err != nil {
+			return nil, err
+		}
+		if pkg, ok = sess.pkgs[filename]; !ok {
+			return nil, nil
+		}
+	}
+
+	var file *ast.File
+	for _, f := range pkg.Syntax {
+		if pkg.Fset.Position(f.Pos()).Filename == filename {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return nil, nil
+	}
+	offset, ok := byteOffset(sess.content(filename), pos)
+	if !ok {
+		return nil, nil
+	}
+	tokFile := pkg.Fset.File(file.Pos())
+	if offset > tokFile.Size() {
+		return nil, nil
+	}
+	at := tokFile.Pos(offset)
+
+	path, _ := astutil.PathEnclosingInterval(file, at, at)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	obj := pkg.TypesInfo.Uses[id]
+	if obj == nil || obj.Pkg() == nil || !obj.Exported() {
+		return nil, nil
+	}
+
+	var (
+		pkgpath = obj.Pkg().Path()
+		typestr string
+		desc    = fmt.Sprintf(`"%s".%s`, pkgpath, id.Name)
+	)
+	if len(path) > 1 {
+		if sel, ok := path[1].(*ast.SelectorExpr); ok && sel.Sel == id {
+			if selection, ok := pkg.TypesInfo.Selections[sel]; ok {
+				typ := selection.Recv()
+				if ptr, ok := typ.(*types.Pointer); ok {
+					typ = ptr.Elem()
+				}
+				typestr = typ.String()
+				if dot := strings.LastIndex(typestr, "."); dot >= 0 {
+					typestr = typestr[dot+1:]
+				}
+				desc = fmt.Sprintf(`"%s".%s.%s`, pkgpath, typestr, id.Name)
+			}
+		}
+	}
+
+	v := sess.s.lookup(pkgpath, id.Name, typestr)
+	if v == 0 {
+		return nil, nil
+	}
+	text := fmt.Sprintf("%s: added in Go 1.%d", desc, v)
+	if sess.s.h.provisional(v) {
+		text += " (provisional)"
+	}
+	return map[string]any{
+		"contents": map[string]any{
+			"kind":  "plaintext",
+			"value": text,
+		},
+		"range": sess.identRange(pkg.Fset.Position(id.Pos())),
+	}, nil
+}
+
+// Method codeActions offers to raise the go directive in go.mod
+// for the findings in the given range.
+func (sess *lspSession) codeActions(uri string, rng lspRange) (any, error) {
+	filename, err := uriFilename(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		gomod   string
+		version int
+	}
+	var (
+		keys  []key
+		diags = make(map[key][]lspDiagnostic)
+	)
+	for _, f := range sess.findings[filename] {
+		if f.diag.Range.End.Line < rng.Start.Line || f.diag.Range.Start.Line > rng.End.Line {
+			continue
+		}
+		k := key{gomod: f.gomod, version: f.version}
+		if _, ok := diags[k]; !ok {
+			keys = append(keys, k)
+		}
+		diags[k] = append(diags[k], f.diag)
+	}
+	slices.SortFunc(keys, func(a, b key) int { return a.version - b.version })
+
+	actions := []lspCodeAction{}
+	for _, k := range keys {
+		if k.gomod == "" {
+			continue
+		}
+		edit, ok, err := sess.raiseGoDirective(k.gomod, k.version)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		action := lspCodeAction{
+			Title:       fmt.Sprintf("Raise go directive to 1.%d", k.version),
+			Kind:        "quickfix",
+			Diagnostics: diags[k],
+		}
+		action.Edit.Changes = map[string][]lspTextEdit{filenameURI(k.gomod): {edit}}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// Method raiseGoDirective produces an edit setting the go directive in gomod to 1.v,
+// or false if it is already at least that.
+func (sess *lspSession) raiseGoDirective(gomod string, v int) (lspTextEdit, bool, error) {
+	data := sess.content(gomod)
+	f, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		return lspTextEdit{}, false, errors.Wrapf(err, "parsing %s", gomod)
+	}
+	if f.Go != nil {
+		declared, err := parseGoVersion(f.Go.Version)
+		if err != nil {
+			return lspTextEdit{}, false, errors.Wrapf(err, "in %s", gomod)
+		}
+		if declared >= v {
+			return lspTextEdit{}, false, nil
+		}
+	}
+	if err := f.AddGoStmt(fmt.Sprintf("1.%d", v)); err != nil {
+		return lspTextEdit{}, false, errors.Wrapf(err, "updating go directive in %s", gomod)
+	}
+	out, err := f.Format()
+	if err != nil {
+		return lspTextEdit{}, false, errors.Wrapf(err, "formatting %s", gomod)
+	}
+
+	// Replace the whole file.
+	end := lspPosition{Line: bytes.Count(data, []byte("\n"))}
+	if i := bytes.LastIndexByte(data, '\n'); i < len(data)-1 {
+		end.Character = utf16Len(data[i+1:])
+	}
+	return lspTextEdit{Range: lspRange{End: end}, NewText: string(out)}, true, nil
+}
+
+// Method content returns the contents of the file:
+// the client's version if it is open,
+// otherwise the one on disk.
+func (sess *lspSession) content(filename string) []byte {
+	if data, ok := sess.overlay[filename]; ok {
+		return data
+	}
+	data, _ := os.ReadFile(filename)
+	return data
+}
+
+// Method identRange converts the position of a finding to an LSP range,
+// extending it over the (possibly qualified) identifier or keyword there.
+func (sess *lspSession) identRange(pos token.Position) lspRange {
+	var (
+		data  = sess.content(pos.Filename)
+		start = pos.Offset
+		end   = start
+	)
+	if start > len(data) {
+		start, end = len(data), len(data)
+	}
+	for end < len(data) {
+		r, size := utf8.DecodeRune(data[end:])
+		if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		end += size
+	}
+	return lspRange{Start: lspPos(data, start), End: lspPos(data, end)}
+}
+
+// Function lspPos converts a byte offset in data to an LSP position.
+func lspPos(data []byte, offset int) lspPosition {
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return lspPosition{
+		Line:      bytes.Count(data[:lineStart], []byte("\n")),
+		Character: utf16Len(data[lineStart:offset]),
+	}
+}
+
+// Function byteOffset converts an LSP position to a byte offset in data.
+func byteOffset(data []byte, pos lspPosition) (int, bool) {
+	offset := 0
+	for range pos.Line {
+		i := bytes.IndexByte(data[offset:], '\n')
+		if i < 0 {
+			return 0, false
+		}
+		offset += i + 1
+	}
+	for n := 0; n < pos.Character && offset < len(data) && data[offset] != '\n'; {
+		r, size := utf8.DecodeRune(data[offset:])
+		n += utf16.RuneLen(r)
+		offset += size
+	}
+	return offset, true
+}
+
+func utf16Len(b []byte) int {
+	var n int
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		n += utf16.RuneLen(r)
+		b = b[size:]
+	}
+	return n
+}
+
+func uriFilename(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing URI %s", uri)
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme in %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+func filenameURI(filename string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}
+	return u.String()
+}
+
+func (sess *lspSession) logf(format string, args ...any) {
+	if !sess.ls.Verbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -0,0 +1,132 @@
+package mingo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLangServer(t *testing.T) {
+	dir, err := filepath.Abs("testdata/lsp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "lsp.go")
+	text, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := filenameURI(filename)
+
+	var (
+		in bytes.Buffer
+		id int
+	)
+	send := func(method string, params any) {
+		msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+		if !strings.HasPrefix(method, "textDocument/did") && method != "initialized" && method != "exit" {
+			id++
+			msg["id"] = id
+		}
+		body, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	send("initialize", map[string]any{"rootUri": filenameURI(dir)})
+	send("initialized", map[string]any{})
+	send("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "go", "version": 1, "text": string(text)},
+	})
+	send("textDocument/hover", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": 8, "character": 10}, // on SortFunc
+	})
+	send("textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"range":        map[string]any{"start": map[string]any{"line": 8}, "end": map[string]any{"line": 8, "character": 40}},
+		"context":      map[string]any{"diagnostics": []any{}},
+	})
+	send("shutdown", nil)
+	send("exit", nil)
+
+	var out bytes.Buffer
+	if err := (LangServer{}).Serve(&in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		br          = bufio.NewReader(&out)
+		diagnostics []lspDiagnostic
+		responses   = make(map[int]json.RawMessage)
+	)
+	for {
+		body, err := readLSPFrame(br)
+		if err != nil {
+			break
+		}
+		var msg struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+			Params struct {
+				URI         string          `json:"uri"`
+				Diagnostics []lspDiagnostic `json:"diagnostics"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case msg.ID != nil:
+			responses[*msg.ID] = msg.Result
+		case msg.Method == "textDocument/publishDiagnostics" && msg.Params.URI == uri:
+			diagnostics = msg.Params.Diagnostics
+		}
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %v", len(diagnostics), diagnostics)
+	}
+	d := diagnostics[0]
+	if want := (lspRange{Start: lspPosition{Line: 8, Character: 1}, End: lspPosition{Line: 8, Character: 16}}); d.Range != want {
+		t.Errorf("got diagnostic range %v, want %v", d.Range, want)
+	}
+	if !strings.Contains(d.Message, "requires Go 1.21, above target 1.20") {
+		t.Errorf("got diagnostic message %q", d.Message)
+	}
+
+	var hover struct {
+		Contents struct {
+			Value string `json:"value"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(responses[2], &hover); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"slices".SortFunc: added in Go 1.21`; hover.Contents.Value != want {
+		t.Errorf("got hover %q, want %q", hover.Contents.Value, want)
+	}
+
+	var actions []lspCodeAction
+	if err := json.Unmarshal(responses[3], &actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 {
+		t.Fatalf("got %d code actions, want 1", len(actions))
+	}
+	if want := "Raise go directive to 1.21"; actions[0].Title != want {
+		t.Errorf("got code action %q, want %q", actions[0].Title, want)
+	}
+	edits := actions[0].Edit.Changes[filenameURI(filepath.Join(dir, "go.mod"))]
+	if len(edits) != 1 || !strings.Contains(edits[0].NewText, "go 1.21\n") {
+		t.Errorf("got edits %v", edits)
+	}
+}
@@ -14,6 +14,15 @@ import (
 	"github.com/bobg/errors"
 )
 
+// These testdata files use features whose versions
+// the type checker in go/types does not enforce the way mingo does.
+var verifyExceptions = map[string]string{
+	"13/bar.go": "go/types allows a constant signed shift count before Go 1.13",
+	"13/baz.go": "go/types allows a constant signed shift count before Go 1.13",
+	"24/foo.go": "go/types allows generic type aliases in Go 1.23, where they needed GOEXPERIMENT=aliastypeparams",
+	"26/bar.go": "go/types does not check the version for recursive type parameters",
+}
+
 func TestLangChecks(t *testing.T) {
 	entries, err := os.ReadDir("testdata")
 	if err != nil {
@@ -88,7 +97,7 @@ func TestLangChecks(t *testing.T) {
 
 					fmt.Fprint(tmpfile, "package foo\n\n")
 
-					combinedImports := append(earlierImports, imports...)
+					combinedImports := append(slices.Clone(earlierImports), imports...)
 					sort.Strings(combinedImports)
 					combinedImports = slices.Compact(combinedImports)
 
@@ -120,13 +129,23 @@ func TestLangChecks(t *testing.T) {
 							}
 						})
 
+						// The type checker should agree with the language version of the code.
+						t.Run("Verify", func(t *testing.T) {
+							if reason, ok := verifyExceptions[minstr+"/"+entry.Name()]; ok {
+								t.Skip(reason)
+							}
+							s := Scanner{Verbose: testing.Verbose(), Verify: true}
+							if _, err := s.ScanDir(tmpdir); err != nil {
+								t.Error(err)
+							}
+						})
+
 						if min == 0 {
 							return
 						}
 
 						// Strict checking should pass with go.mod declaring the min version.
 						t.Run("StrictCheckOK", func(t *testing.T) {
-							s.reset()
 							s.Check = true
 							s.Strict = true
 
@@ -0,0 +1,151 @@
+package mingo
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bobg/errors"
+	"golang.org/x/mod/semver"
+)
+
+// Type modHistory is the API history of a non-stdlib module,
+// as parsed from a directory of per-version files
+// in the same format as the go1.*.txt files in $GOROOT/api.
+type modHistory struct {
+	modpath string
+
+	// The module's versions, in increasing semver order.
+	versions []string
+
+	// The "minor versions" in this history are 1-based indexes into versions.
+	h *history
+}
+
+// Method lookup returns the module version that introduced the given identifier,
+// or "" if it is unknown.
+func (m *modHistory) lookup(pkgpath, id, typ string) string {
+	if v := m.h.lookup(pkgpath, id, typ); v > 0 {
+		return m.versions[v-1]
+	}
+	return ""
+}
+
+// Method contains tells whether pkgpath is a package in this module.
+func (m *modHistory) contains(pkgpath string) bool {
+	return pkgpath == m.modpath || strings.HasPrefix(pkgpath, m.modpath+"/")
+}
+
+var modAPIFilenameRegex = regexp.MustCompile(`^(v\d+\.\d+\.\d+.*)\.txt$`)
+
+// Function readModHist reads the API history of the module modpath
+// from the sequence of v*.txt files in the given directory.
+func readModHist(modpath, dir string) (*modHistory, error) {
+	return readModHistFS(modpath, os.DirFS(dir), ".")
+}
+
+// Function readModHistFS reads the API history of the module modpath
+// from the sequence of v*.txt files
+// in the given directory within the given filesystem.
+// Each file is named for the module version whose API additions it lists,
+// e.g. v1.2.0.txt.
+func readModHistFS(modpath string, fsys fs.FS, dir string) (*modHistory, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &modHistory{
+		modpath: modpath,
+		h:       &history{pkgs: make(map[string]pkgHistory)},
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := modAPIFilenameRegex.FindStringSubmatch(entry.Name())
+		if len(match) == 0 || !semver.IsValid(match[1]) {
+			continue
+		}
+		m.versions = append(m.versions, match[1])
+	}
+
+	// Histories are "first seen wins," so read them in version order.
+	sortVersions(m.versions)
+
+	for i, version := range m.versions {
+		if err := readHistVersion(m.h, fsys, path.Join(dir, version+".txt"), i+1); err != nil {
+			return nil, errors.Wrapf(err, "reading %s history of %s", version, modpath)
+		}
+	}
+	m.h.max = len(m.versions)
+
+	return m, nil
+}
+
+// ModResult is the minimum version of a non-stdlib module required by the scanned code,
+// as computed from that module's API history
+// (see [Scanner.ModHist]).
+type ModResult struct {
+	ModPath string         // The module path.
+	Version string         // The lowest version of the module required.
+	Pos     token.Position // The position of the reference that requires Version.
+	Desc    string         // A description of that reference.
+}
+
+func (r ModResult) String() string {
+	b := new(bytes.Buffer)
+
+	fmt.Fprintf(b, "%s: %s@%s", r.Pos, r.ModPath, r.Version)
+	if r.Desc != "" {
+		fmt.Fprintf(b, " (%s)", r.Desc)
+	}
+
+	return b.String()
+}
+
+// Method modHist finds the module history, if any, for the module containing pkgpath.
+// When module paths are nested,
+// the longest match wins.
+func (s *Scanner) modHist(pkgpath string) *modHistory {
+	var result *modHistory
+	for _, m := range s.mh {
+		if !m.contains(pkgpath) {
+			continue
+		}
+		if result == nil || len(m.modpath) > len(result.modpath) {
+			result = m
+		}
+	}
+	return result
+}
+
+// Method modResult records a reference to a symbol in a module with a history,
+// updating p.modResults if it raises that module's minimum version.
+func (p *pkgScanner) modResult(pkgpath, id, typ string, pos token.Position, desc string) {
+	m := p.s.modHist(pkgpath)
+	if m == nil {
+		return
+	}
+	v := m.lookup(pkgpath, id, typ)
+	if v == "" {
+		return
+	}
+	if prev, ok := p.modResults[m.modpath]; ok && semver.Compare(v, prev.Version) <= 0 {
+		return
+	}
+	r := ModResult{
+		ModPath: m.modpath,
+		Version: v,
+		Pos:     pos,
+		Desc:    desc,
+	}
+	p.modResults[m.modpath] = r
+	p.s.verbosef("%s", r)
+}
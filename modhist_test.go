@@ -0,0 +1,65 @@
+package mingo
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestModHist(t *testing.T) {
+	s := Scanner{
+		ModHist: map[string]string{"example.com/lib": "testdata/modhist/api"},
+	}
+	res, err := s.ScanDirContext(context.Background(), "testdata/modhist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := res.Version(); v != 1 {
+		t.Errorf("got Go version %d, want 1", v)
+	}
+	modres, ok := res.ModResults["example.com/lib"]
+	if !ok {
+		t.Fatal("no result for example.com/lib")
+	}
+	if modres.Version != "v1.1.0" {
+		t.Errorf("got example.com/lib version %s, want v1.1.0", modres.Version)
+	}
+}
+
+func TestReadModHist(t *testing.T) {
+	fsys := fstest.MapFS{
+		"v1.10.0.txt":   {Data: []byte("pkg foo/bar, func C() int\n")},
+		"v1.2.0.txt":    {Data: []byte("pkg foo/bar, func B() int\npkg foo/bar, func C() int\n")},
+		"v1.0.0.txt":    {Data: []byte("pkg foo/bar, func A() int\n")},
+		"notes.txt":     {Data: []byte("not a history file\n")},
+		"v2.txt":        {Data: []byte("not a history file either\n")},
+		"v1.3.0-rc.txt": {Data: []byte("pkg foo/bar, func D() int\n")},
+	}
+
+	m, err := readModHistFS("foo", fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		pkgpath, id, want string
+	}{
+		{"foo/bar", "A", "v1.0.0"},
+		{"foo/bar", "B", "v1.2.0"},
+		{"foo/bar", "C", "v1.2.0"},
+		{"foo/bar", "D", "v1.3.0-rc"},
+		{"foo/bar", "E", ""},
+		{"foo/baz", "A", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.pkgpath+"."+tc.id, func(t *testing.T) {
+			if got := m.lookup(tc.pkgpath, tc.id, ""); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if !m.contains("foo/bar") || !m.contains("foo") || m.contains("foobar") {
+		t.Error("contains gives wrong answers")
+	}
+}
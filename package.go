@@ -1,23 +1,139 @@
 package mingo
 
 import (
+	"context"
 	"go/ast"
+	"go/build/constraint"
 	"go/token"
 	"go/types"
+	"strings"
 
 	"github.com/bobg/errors"
 )
 
+// Type pkgScanner scans a single package.
+// It keeps its results to itself,
+// so that several can run at once
+// (as in an [analysis.Analyzer]).
+// [Scanner.ScanPackages] merges them with [Scanner.merge].
 type pkgScanner struct {
-	s       *Scanner
-	pkgpath string
-	fset    *token.FileSet
-	info    *types.Info
+	s         *Scanner
+	ctx       context.Context // checked before each file and top-level declaration
+	pkg       *types.Package
+	fset      *token.FileSet
+	info      *types.Info
+	langRules []Rule
+
+	res        Result
+	lang       Result               // the highest result from a rule the type checker can verify, see [Scanner.Verify]
+	guard      int                  // the Go version required by the current file's build constraint, if any
+	inference  map[token.Pos]bool   // where type inference needs Go 1.21, see [pkgScanner.inferenceErrors]
+	modResults map[string]ModResult // see [Scanner.ModResults]
+	refs       map[depRef]posResult // with DepVersions, references to identifiers in non-stdlib packages
+
+	// Uses of features that stop working in some version of Go,
+	// like pull-style //go:linkname references to the stdlib.
+	// Their versions are upper bounds, not minimums.
+	warnings []posResult
+
+	// With report, every result above target is added to findings.
+	report   bool
+	target   int
+	findings []posResult
+}
+
+func (s *Scanner) newPkgScanner(pkg *types.Package, fset *token.FileSet, info *types.Info) *pkgScanner {
+	return &pkgScanner{
+		s:          s,
+		ctx:        context.Background(),
+		pkg:        pkg,
+		fset:       fset,
+		info:       info,
+		langRules:  Rules(),
+		res:        intResult(0),
+		lang:       intResult(0),
+		modResults: make(map[string]ModResult),
+		refs:       make(map[depRef]posResult),
+	}
+}
+
+// Method files scans the given files of the package,
+// skipping any that are in GOCACHE.
+func (p *pkgScanner) files(files []*ast.File) error {
+	p.inference = p.inferenceErrors(files)
+
+	files, err := p.sourceFiles(files)
+	if err != nil {
+		return err
+	}
+	p.warn(files)
+
+	for _, file := range files {
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		filename := p.fset.Position(file.Pos()).Filename
+		p.guard = goBuildVersion(file)
+		if p.buildTags(file, filename) {
+			return nil
+		}
+		if isMax, err := p.file(file); err != nil || isMax {
+			return errors.Wrapf(err, "scanning file %s", filename)
+		}
+	}
+	return nil
+}
+
+// Method warnFiles collects only the warnings from the given files of the package,
+// skipping any that are in GOCACHE.
+func (p *pkgScanner) warnFiles(files []*ast.File) error {
+	files, err := p.sourceFiles(files)
+	if err != nil {
+		return err
+	}
+	p.warn(files)
+	return nil
+}
+
+// Method warn collects the warnings from all of files.
+// This is separate from the rest of the scan,
+// which stops at the max known Go version,
+// so that the warnings do not depend on the order of the files.
+func (p *pkgScanner) warn(files []*ast.File) {
+	for _, file := range files {
+		p.linknames(file)
+	}
+}
+
+// Method sourceFiles returns files less any that are in GOCACHE
+// (such as the generated files of cgo).
+func (p *pkgScanner) sourceFiles(files []*ast.File) ([]*ast.File, error) {
+	var result []*ast.File
+	for _, file := range files {
+		filename := p.fset.Position(file.Pos()).Filename
+		isInCache, err := isCacheFile(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking whether %s is in GOCACHE", filename)
+		}
+		if !isInCache {
+			result = append(result, file)
+		}
+	}
+	return result, nil
 }
 
 // Bool result tells whether the max known Go version has been reached.
 func (p *pkgScanner) file(file *ast.File) (bool, error) {
+	if p.report {
+		p.possibleLoopVarCaptures(file)
+	}
+	if p.rules(file, p.langRules) {
+		return true, nil
+	}
 	for _, decl := range file.Decls {
+		if err := p.ctx.Err(); err != nil {
+			return false, err
+		}
 		if isMax, err := p.decl(decl); err != nil || isMax {
 			return isMax, errors.Wrapf(err, "scanning decl at %s", p.fset.Position(decl.Pos()))
 		}
@@ -26,29 +142,81 @@ func (p *pkgScanner) file(file *ast.File) (bool, error) {
 }
 
 func (p *pkgScanner) result(r Result) bool {
-	return p.s.result(r)
+	if r.Version() <= p.guard {
+		// This file is built only by Go versions that have the feature.
+		return false
+	}
+	if r.Version() > p.res.Version() {
+		p.res = r
+		p.s.verbosef("%s", r)
+	}
+	if pr, ok := r.(posResult); ok && p.report && pr.version > p.target {
+		p.findings = append(p.findings, pr)
+	}
+	return p.isMax()
 }
 
-func (p *pkgScanner) isMax() bool {
-	return p.s.isMax()
+// Method apiResult records a use of a stdlib identifier introduced in Go 1.v.
+func (p *pkgScanner) apiResult(v int, pos token.Pos, desc string) bool {
+	return p.result(posResult{
+		version:     v,
+		pos:         p.fset.Position(pos),
+		at:          pos,
+		desc:        desc,
+		provisional: p.s.h.provisional(v),
+	})
 }
 
-func (p *pkgScanner) isTypeExpr(expr ast.Expr) bool {
-	tv, ok := p.info.Types[expr]
-	if !ok {
-		return false
+// Method ref records a reference to an exported identifier in another package,
+// for [Scanner.ModHist] and [Scanner.DepVersions].
+func (p *pkgScanner) ref(pkgpath, id, typ string, pos token.Pos, desc string) {
+	position := p.fset.Position(pos)
+	p.modResult(pkgpath, id, typ, position, desc)
+	p.depRef(pkgpath, id, typ, posResult{pos: position, at: pos, desc: desc})
+}
+
+// Function goBuildVersion returns the minor version of Go 1.x
+// that the file's //go:build constraint requires,
+// e.g. 21 for "//go:build go1.21 && linux",
+// or 0 if it does not require one.
+func goBuildVersion(file *ast.File) int {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				return 0
+			}
+			return constraintVersion(expr)
+		}
 	}
-	return tv.IsType()
+	return 0
 }
 
-func (p *pkgScanner) isSigned(expr ast.Expr) bool {
-	tv, ok := p.info.Types[expr]
-	if !ok {
-		return false
+func constraintVersion(expr constraint.Expr) int {
+	switch expr := expr.(type) {
+	case *constraint.TagExpr:
+		if v, err := parseGoVersion(expr.Tag); err == nil && strings.HasPrefix(expr.Tag, "go1.") {
+			return v
+		}
+	case *constraint.AndExpr:
+		return max(constraintVersion(expr.X), constraintVersion(expr.Y))
+	case *constraint.OrExpr:
+		return min(constraintVersion(expr.X), constraintVersion(expr.Y))
 	}
-	basic, ok := tv.Type.(*types.Basic)
-	if !ok {
+	return 0
+}
+
+func (p *pkgScanner) isMax() bool {
+	if p.report || p.s.Verify {
+		// Keep going to find every use of a feature above the target,
+		// or the package's true language version.
 		return false
 	}
-	return basic.Info()&types.IsInteger != 0 && basic.Info()&types.IsUnsigned == 0
+	return p.s.isMax(p.res.Version())
 }
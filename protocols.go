@@ -0,0 +1,181 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// These are the rules for methods that the stdlib looks for by convention,
+// like the Unwrap() []error of errors that wrap several others.
+// A type with such a method compiles with any version of Go,
+// but the stdlib calls the method only as of the rule's version,
+// so the rules are [Rule.Semantic].
+//
+// The io.WriterTo and io.ReaderFrom fast paths of io.Copy
+// are honored by every version of Go 1.x
+// and so are not listed.
+var protocolRules = []Rule{{
+	ID:       "protocol-unwrap-error",
+	Version:  13,
+	Desc:     "Unwrap() error method on an error type, honored by errors.Is and errors.As",
+	Link:     "https://go.dev/doc/go1.13#error_wrapping",
+	Match:    matchProtocol(protocolShape{method: "Unwrap", results: []string{"error"}, requires: errorMethods}),
+	Semantic: true,
+}, {
+	ID:       "protocol-error-is",
+	Version:  13,
+	Desc:     "Is(error) bool method on an error type, honored by errors.Is",
+	Link:     "https://go.dev/doc/go1.13#error_wrapping",
+	Match:    matchProtocol(protocolShape{method: "Is", params: []string{"error"}, results: []string{"bool"}, requires: errorMethods}),
+	Semantic: true,
+}, {
+	ID:       "protocol-error-as",
+	Version:  13,
+	Desc:     "As(any) bool method on an error type, honored by errors.As",
+	Link:     "https://go.dev/doc/go1.13#error_wrapping",
+	Match:    matchProtocol(protocolShape{method: "As", params: []string{"any"}, results: []string{"bool"}, requires: errorMethods}),
+	Semantic: true,
+}, {
+	ID:       "protocol-unwrap-errors",
+	Version:  20,
+	Desc:     "Unwrap() []error method on an error type, honored by errors.Is and errors.As",
+	Link:     "https://go.dev/doc/go1.20#errors",
+	Match:    matchProtocol(protocolShape{method: "Unwrap", results: []string{"[]error"}, requires: errorMethods}),
+	Semantic: true,
+}, {
+	ID:       "protocol-response-writer-unwrap",
+	Version:  20,
+	Desc:     "Unwrap() http.ResponseWriter method on a ResponseWriter, honored by http.ResponseController",
+	Link:     "https://go.dev/doc/go1.20#http_responsecontroller",
+	Match:    matchProtocol(protocolShape{method: "Unwrap", results: []string{"net/http.ResponseWriter"}, requires: responseWriterMethods}),
+	Semantic: true,
+}, {
+	ID:      "protocol-response-writer-deadline",
+	Version: 20,
+	Desc:    "SetReadDeadline or SetWriteDeadline method on a ResponseWriter, honored by http.ResponseController",
+	Link:    "https://go.dev/doc/go1.20#http_responsecontroller",
+	Match: matchProtocol(
+		protocolShape{method: "SetReadDeadline", params: []string{"time.Time"}, results: []string{"error"}, requires: responseWriterMethods},
+		protocolShape{method: "SetWriteDeadline", params: []string{"time.Time"}, results: []string{"error"}, requires: responseWriterMethods},
+	),
+	Semantic: true,
+}, {
+	ID:       "protocol-response-writer-flush-error",
+	Version:  20,
+	Desc:     "FlushError() error method on a ResponseWriter, honored by http.ResponseController",
+	Link:     "https://go.dev/doc/go1.20#http_responsecontroller",
+	Match:    matchProtocol(protocolShape{method: "FlushError", results: []string{"error"}, requires: responseWriterMethods}),
+	Semantic: true,
+}, {
+	ID:       "protocol-response-writer-full-duplex",
+	Version:  21,
+	Desc:     "EnableFullDuplex() error method on a ResponseWriter, honored by http.ResponseController",
+	Link:     "https://go.dev/doc/go1.21#net/http",
+	Match:    matchProtocol(protocolShape{method: "EnableFullDuplex", results: []string{"error"}, requires: responseWriterMethods}),
+	Semantic: true,
+}, {
+	ID:       "protocol-log-valuer",
+	Version:  21,
+	Desc:     "LogValue() slog.Value method, honored by log/slog",
+	Link:     "https://go.dev/doc/go1.21#slog",
+	Match:    matchProtocol(protocolShape{method: "LogValue", results: []string{"log/slog.Value"}}),
+	Semantic: true,
+}, {
+	ID:      "protocol-appender",
+	Version: 24,
+	Desc:    "AppendText or AppendBinary method, honored as encoding.TextAppender or encoding.BinaryAppender",
+	Link:    "https://go.dev/doc/go1.24#encoding",
+	Match: matchProtocol(
+		protocolShape{method: "AppendText", params: []string{"[]byte"}, results: []string{"[]byte", "error"}},
+		protocolShape{method: "AppendBinary", params: []string{"[]byte"}, results: []string{"[]byte", "error"}},
+	),
+	Semantic: true,
+}}
+
+func init() {
+	for _, r := range protocolRules {
+		Register(r)
+	}
+}
+
+var (
+	errorMethods          = []string{"Error"}
+	responseWriterMethods = []string{"Header", "Write", "WriteHeader"}
+)
+
+// Type protocolShape describes a method that the stdlib looks for.
+// Types are written as by [types.TypeString] with no qualifier
+// (e.g. "log/slog.Value"),
+// except that "any" means any empty interface.
+type protocolShape struct {
+	method          string
+	params, results []string
+	requires        []string // other methods the receiver type must have for the stdlib to look for this one
+}
+
+// Function matchProtocol returns the Match function for the declaration of a method
+// with any of the given shapes.
+func matchProtocol(shapes ...protocolShape) func(*RuleContext, ast.Node) (token.Pos, bool) {
+	return func(c *RuleContext, node ast.Node) (token.Pos, bool) {
+		decl, ok := node.(*ast.FuncDecl)
+		if !ok || decl.Recv == nil {
+			return token.NoPos, false
+		}
+		fn, ok := c.Info.Defs[decl.Name].(*types.Func)
+		if !ok {
+			return token.NoPos, false
+		}
+		for _, shape := range shapes {
+			if shape.matches(fn) {
+				return decl.Name.Pos(), true
+			}
+		}
+		return token.NoPos, false
+	}
+}
+
+func (shape protocolShape) matches(fn *types.Func) bool {
+	if fn.Name() != shape.method {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Variadic() || !tupleMatches(sig.Params(), shape.params) || !tupleMatches(sig.Results(), shape.results) {
+		return false
+	}
+	if len(shape.requires) == 0 {
+		return true
+	}
+
+	recv := sig.Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	mset := types.NewMethodSet(types.NewPointer(recv))
+	for _, name := range shape.requires {
+		if mset.Lookup(nil, name) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func tupleMatches(tuple *types.Tuple, want []string) bool {
+	if tuple.Len() != len(want) {
+		return false
+	}
+	for i, w := range want {
+		typ := tuple.At(i).Type()
+		if w == "any" {
+			iface, ok := typ.Underlying().(*types.Interface)
+			if !ok || iface.NumMethods() > 0 || !iface.IsMethodSet() {
+				return false
+			}
+			continue
+		}
+		if types.TypeString(typ, nil) != w {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"testing"
+)
+
+func TestProtocols(t *testing.T) {
+	cases := []struct {
+		name, src string
+		want      []string
+	}{{
+		name: "unwrap-error",
+		src:  "type E struct{}\n\nfunc (E) Error() string { return \"\" }\n\nfunc (E) Unwrap() error { return nil }",
+		want: []string{"protocol-unwrap-error"},
+	}, {
+		name: "unwrap-non-error",
+		src:  "type T struct{}\n\nfunc (T) Unwrap() error { return nil }",
+	}, {
+		name: "unwrap-errors",
+		src:  "type E struct{}\n\nfunc (*E) Error() string { return \"\" }\n\nfunc (*E) Unwrap() []error { return nil }",
+		want: []string{"protocol-unwrap-errors"},
+	}, {
+		name: "is-as",
+		src:  "type E struct{}\n\nfunc (E) Error() string { return \"\" }\n\nfunc (E) Is(error) bool { return false }\n\nfunc (E) As(interface{}) bool { return false }",
+		want: []string{"protocol-error-is", "protocol-error-as"},
+	}, {
+		name: "response-writer",
+		src:  "type W struct{ http.ResponseWriter }\n\nfunc (w W) Unwrap() http.ResponseWriter { return w.ResponseWriter }\n\nfunc (W) SetWriteDeadline(time.Time) error { return nil }\n\nfunc (W) EnableFullDuplex() error { return nil }\n\nfunc (W) FlushError() error { return nil }",
+		want: []string{"protocol-response-writer-unwrap", "protocol-response-writer-deadline", "protocol-response-writer-full-duplex", "protocol-response-writer-flush-error"},
+	}, {
+		name: "deadline-non-response-writer",
+		src:  "type C struct{}\n\nfunc (C) SetReadDeadline(time.Time) error { return nil }",
+	}, {
+		name: "log-valuer",
+		src:  "type T struct{}\n\nfunc (T) LogValue() slog.Value { return slog.Value{} }",
+		want: []string{"protocol-log-valuer"},
+	}, {
+		name: "appender",
+		src:  "type T struct{}\n\nfunc (T) AppendText(b []byte) ([]byte, error) { return b, nil }\n\nfunc (T) AppendBinary(b []byte) (out []byte, err error) { return b, nil }",
+		want: []string{"protocol-appender", "protocol-appender"},
+	}, {
+		name: "wrong-shape",
+		src:  "type T struct{}\n\nfunc (T) AppendText(b []byte) []byte { return b }\n\nfunc (T) WriteTo(w io.Writer) (int64, error) { return 0, nil }",
+	}}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := "package p\n\nimport (\n\t\"io\"\n\t\"log/slog\"\n\t\"net/http\"\n\t\"time\"\n)\n\nvar (\n\t_ io.Writer\n\t_ slog.Value\n\t_ http.ResponseWriter\n\t_ time.Time\n)\n\n" + tc.src + "\n"
+
+			file, err := parser.ParseFile(fset, tc.name+".go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Defs: make(map[*ast.Ident]types.Object),
+				Uses: make(map[*ast.Ident]types.Object),
+			}
+			conf := &types.Config{Importer: imp}
+			if _, err := conf.Check("p", fset, []*ast.File{file}, info); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &RuleContext{Fset: fset, Info: info, file: file}
+			var got []string
+			ast.Inspect(file, func(node ast.Node) bool {
+				for _, r := range protocolRules {
+					if _, ok := r.Match(c, node); ok {
+						got = append(got, r.ID)
+					}
+				}
+				return true
+			})
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
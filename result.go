@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"go/token"
 	"strconv"
+
+	"golang.org/x/tools/go/analysis"
 )
 
 // Result is the type of a result returned by [Scanner.ScanDir] and [Scanner.ScanPackages].
@@ -19,9 +21,14 @@ func (r intResult) Version() int   { return int(r) }
 func (r intResult) String() string { return strconv.Itoa(int(r)) }
 
 type posResult struct {
-	version int
-	pos     token.Position
-	desc    string
+	version     int
+	pos         token.Position
+	at          token.Pos // pos, in the FileSet of the scanned package (for diagnostics)
+	desc        string
+	provisional bool                // version is an unreleased one whose API may still change
+	rule        string              // the ID of the language rule that produced this result, if any
+	semantic    bool                // the rule is a change in behavior, not a compile error, below version (see [Rule.Semantic])
+	fix         []analysis.TextEdit // edits avoiding the feature, from the rule's Fix function
 }
 
 func (r posResult) Version() int { return r.version }
@@ -33,6 +40,22 @@ func (r posResult) String() string {
 	if r.desc != "" {
 		fmt.Fprintf(b, " (%s)", r.desc)
 	}
+	if r.provisional {
+		b.WriteString(" [provisional]")
+	}
 
 	return b.String()
 }
+
+// Type historyWarning is the warning in [ScanResult.Warnings]
+// that the Go toolchain running the scan is newer than the API history.
+type historyWarning struct {
+	runtime, max int
+	histDir      string
+}
+
+func (w historyWarning) Version() int { return w.runtime }
+
+func (w historyWarning) String() string {
+	return fmt.Sprintf("runtime Go version 1.%d is newer than history max 1.%d (reading from HistDir %q); results may understate the minimum", w.runtime, w.max, w.histDir)
+}
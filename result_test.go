@@ -24,3 +24,16 @@ func TestPosResult(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestProvisionalPosResult(t *testing.T) {
+	r := posResult{
+		version:     28,
+		pos:         token.Position{Filename: "foo.go", Line: 17},
+		desc:        "foobar",
+		provisional: true,
+	}
+	const want = "foo.go:17: 28 (foobar) [provisional]"
+	if got := r.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+package mingo
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Rule describes a Go language feature introduced at a particular version of Go,
+// together with a way to detect uses of it.
+//
+// Mingo's own rules are listed by [Rules].
+// Add more with [Register].
+type Rule struct {
+	ID      string // A unique identifier, e.g. "range-over-int".
+	Version int    // The minor version of Go 1.x that introduced the feature.
+	Desc    string // A short description, e.g. "range over integer".
+	Link    string // A reference to the spec or release notes describing the feature.
+
+	// Semantic means the feature is a change in the behavior of code
+	// that also compiles with earlier versions of Go
+	// (like the per-iteration loop variables of Go 1.22).
+	// Lowering the go directive below Version
+	// silently changes what such code does
+	// instead of breaking the build,
+	// so findings above a target are worded as warnings.
+	Semantic bool
+
+	// Match tells whether node uses the feature,
+	// and if so, the position to report.
+	// It is called for every node in the syntax tree of every scanned file,
+	// with the ancestors of node available from c,
+	// and then for every *ast.Comment in the file
+	// (with no ancestors).
+	Match func(c *RuleContext, node ast.Node) (token.Pos, bool)
+
+	// Fix, if non-nil, produces edits that rewrite node
+	// (for which Match returned true)
+	// to an equivalent that does not need the feature.
+	// It returns nil when there is no such rewrite.
+	// [Analyzer] attaches the edits to its diagnostics as an [analysis.SuggestedFix].
+	Fix func(c *RuleContext, node ast.Node) []analysis.TextEdit
+}
+
+// RuleContext is the information available to a [Rule]'s Match function.
+type RuleContext struct {
+	Fset *token.FileSet
+	Info *types.Info
+
+	file      *ast.File
+	stack     []ast.Node         // ancestors of the node being matched, outermost first
+	inference map[token.Pos]bool // see [pkgScanner.inferenceErrors]
+}
+
+// Parent returns the parent of the node being matched,
+// or nil if it is the root.
+func (c *RuleContext) Parent() ast.Node {
+	if len(c.stack) == 0 {
+		return nil
+	}
+	return c.stack[len(c.stack)-1]
+}
+
+func (c *RuleContext) isTypeExpr(expr ast.Expr) bool {
+	tv, ok := c.Info.Types[expr]
+	if !ok {
+		return false
+	}
+	return tv.IsType()
+}
+
+func (c *RuleContext) isSigned(expr ast.Expr) bool {
+	tv, ok := c.Info.Types[expr]
+	if !ok {
+		return false
+	}
+	basic, ok := tv.Type.(*types.Basic)
+	if !ok {
+		return false
+	}
+	return basic.Info()&types.IsInteger != 0 && basic.Info()&types.IsUnsigned == 0
+}
+
+// Method source returns the source text of node, as formatted by go/printer.
+func (c *RuleContext) source(node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, c.Fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// Method typeString returns the string for typ in the file being scanned,
+// qualifying package-level names with the names under which their packages are imported.
+// It returns false if typ refers to a package the file does not import.
+func (c *RuleContext) typeString(typ types.Type) (string, bool) {
+	var (
+		pkgScope = c.Info.Scopes[c.file].Parent()
+		ok       = true
+	)
+	qualifier := func(pkg *types.Package) string {
+		if pkg.Scope() == pkgScope {
+			return ""
+		}
+		for _, spec := range c.file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err != nil || path != pkg.Path() {
+				continue
+			}
+			switch {
+			case spec.Name == nil:
+				return pkg.Name()
+			case spec.Name.Name == ".":
+				return ""
+			case spec.Name.Name != "_":
+				return spec.Name.Name
+			}
+		}
+		ok = false
+		return pkg.Name()
+	}
+	result := types.TypeString(typ, qualifier)
+	return result, ok
+}
+
+var registry struct {
+	mu    sync.Mutex
+	rules map[string]Rule
+}
+
+// Register adds a rule to the set used by every [Scanner].
+// It panics if r has no ID or Match function,
+// or if a rule with the same ID is already registered.
+func Register(r Rule) {
+	if r.ID == "" {
+		panic("mingo: rule has no ID")
+	}
+	if r.Match == nil {
+		panic(fmt.Sprintf("mingo: rule %s has no Match function", r.ID))
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if registry.rules == nil {
+		registry.rules = make(map[string]Rule)
+	}
+	if _, ok := registry.rules[r.ID]; ok {
+		panic(fmt.Sprintf("mingo: rule %s registered twice", r.ID))
+	}
+	registry.rules[r.ID] = r
+}
+
+// Rules returns the registered rules,
+// sorted by version and then by ID.
+func Rules() []Rule {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	result := make([]Rule, 0, len(registry.rules))
+	for _, r := range registry.rules {
+		result = append(result, r)
+	}
+	slices.SortFunc(result, func(a, b Rule) int {
+		if a.Version != b.Version {
+			return a.Version - b.Version
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return result
+}
+
+//go:generate go run ./cmd/mingo rules -o Rules.md
+
+// RulesDoc writes a Markdown document listing the registered rules to w.
+func RulesDoc(w io.Writer) error {
+	const header = `# Language features detected by mingo
+
+This file is generated by ` + "`mingo rules`" + `.
+In addition to these language features,
+mingo reports uses of standard-library identifiers
+according to the version of Go that introduced each one.
+
+| Go version | ID | Feature |
+|------------|----|---------|
+`
+	if _, err := fmt.Fprint(w, header); err != nil {
+		return err
+	}
+	for _, r := range Rules() {
+		desc := r.Desc
+		if r.Link != "" {
+			desc = fmt.Sprintf("[%s](%s)", desc, r.Link)
+		}
+		if _, err := fmt.Fprintf(w, "| 1.%d | `%s` | %s |\n", r.Version, r.ID, desc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method rules applies the registered rules to every node in file.
+// Bool result tells whether the max known Go version has been reached.
+func (p *pkgScanner) rules(file *ast.File, rules []Rule) bool {
+	var (
+		c     = &RuleContext{Fset: p.fset, Info: p.info, file: file, inference: p.inference}
+		isMax bool
+	)
+	match := func(node ast.Node) bool {
+		for _, r := range rules {
+			pos, ok := r.Match(c, node)
+			if !ok {
+				continue
+			}
+			res := posResult{
+				version:  r.Version,
+				pos:      p.fset.Position(pos),
+				at:       pos,
+				desc:     r.Desc,
+				rule:     r.ID,
+				semantic: r.Semantic,
+			}
+			if p.report && r.Fix != nil {
+				res.fix = r.Fix(c, node)
+			}
+			if _, isComment := node.(*ast.Comment); !isComment && !r.Semantic && r.Version > max(p.guard, p.lang.Version()) {
+				// The type checker knows nothing of directives or changes in behavior.
+				p.lang = res
+			}
+			if p.result(res) {
+				isMax = true
+				return false
+			}
+		}
+		return true
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		if isMax {
+			return false
+		}
+		if node == nil {
+			c.stack = c.stack[:len(c.stack)-1]
+			return true
+		}
+		if _, ok := node.(*ast.CommentGroup); ok {
+			// Comments are matched below,
+			// including the ones not attached to any node.
+			return false
+		}
+		if !match(node) {
+			return false
+		}
+		c.stack = append(c.stack, node)
+		return true
+	})
+
+	c.stack = nil
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if isMax || !match(comment) {
+				return isMax
+			}
+		}
+	}
+	return isMax
+}
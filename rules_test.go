@@ -0,0 +1,392 @@
+package mingo
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRulesDoc(t *testing.T) {
+	want, err := os.ReadFile("Rules.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(bytes.Buffer)
+	if err := RulesDoc(got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Error("Rules.md is out of date; run go generate")
+	}
+}
+
+func TestRulesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, r := range Rules() {
+		if seen[r.ID] {
+			t.Errorf("duplicate rule ID %s", r.ID)
+		}
+		seen[r.ID] = true
+		if r.Version <= 0 {
+			t.Errorf("rule %s has version %d", r.ID, r.Version)
+		}
+		if r.Desc == "" {
+			t.Errorf("rule %s has no description", r.ID)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	// A custom rule banning goto statements "as of" Go 1.99.
+	r := Rule{
+		ID:      "test-no-goto",
+		Version: 99,
+		Desc:    "goto statement",
+		Match: func(_ *RuleContext, node ast.Node) (token.Pos, bool) {
+			if stmt, ok := node.(*ast.BranchStmt); ok && stmt.Tok == token.GOTO {
+				return stmt.Pos(), true
+			}
+			return token.NoPos, false
+		},
+	}
+	Register(r)
+	defer unregister(r.ID)
+
+	t.Run("duplicate", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic on duplicate registration")
+			}
+		}()
+		Register(r)
+	})
+
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/go.mod", []byte("module foo\n\ngo 1.18\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir+"/foo.go", []byte("package foo\n\nfunc f() {\nL:\n\tgoto L\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var s Scanner
+	res, err := s.ScanDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version() != 99 {
+		t.Errorf("got %d, want 99", res.Version())
+	}
+	if pr, ok := res.(posResult); !ok || pr.rule != "test-no-goto" {
+		t.Errorf("got result %v, want one from rule test-no-goto", res)
+	}
+}
+
+// Function unregister removes a rule from the registry,
+// returning it so that it can be registered again.
+func unregister(id string) Rule {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	r := registry.rules[id]
+	delete(registry.rules, id)
+	return r
+}
+
+func TestLoopVarCapture(t *testing.T) {
+	// Each case wants "escape" (matchLoopVarCapture),
+	// "maybe" (possibleLoopVarCapture),
+	// or neither.
+	cases := []struct {
+		name, body string
+		want       string
+	}{
+		{"closure", "for i := 0; i < 3; i++ { fns = append(fns, func() int { return i }) }", "escape"},
+		{"go", "for _, v := range ts { go func() { use(v) }() }", "escape"},
+		{"go-address", "for _, v := range ts { go usePtr(&v) }", "escape"},
+		{"defer-method", "for _, v := range ts { defer v.M() }", "escape"},
+		{"address", "for _, v := range ts { ptrs = append(ptrs, &v.x) }", "escape"},
+		{"array-elem", "for _, a := range arrs { ptrs = append(ptrs, &a[0]) }", "escape"},
+		{"send", "for _, v := range ts { ch <- func() int { return v.x } }", "escape"},
+		{"field", "var s struct{ f func() int }; for _, v := range ts { s.f = func() int { return v.x } }; _ = s", "escape"},
+		{"composite", "for _, v := range ts { structs = append(structs, S{p: &v}) }", "escape"},
+		{"method-value", "for _, v := range ts { f := v.M; _ = f }", "maybe"},
+		{"local-address", "for _, v := range ts { p := &v; usePtr(p) }", "maybe"},
+		{"local-assign", "for _, v := range ts { var f func() int; f = func() int { return v.x }; _ = f }", "maybe"},
+		{"sort-slice", "for _, y := range ys { sort.Slice(y, func(i, j int) bool { return y[i] < y[j] }) }", "maybe"},
+		{"t-run", "for _, v := range ts { tt.Run(\"x\", func(*testing.T) { use(v) }) }", "maybe"},
+		{"callback", "for _, v := range ts { apply(func() { use(v) }) }", "maybe"},
+		{"immediate", "for _, v := range ts { func() { use(v) }() }", ""},
+		{"go-arg", "for _, v := range ts { go use(v) }", ""},
+		{"method-call", "for _, v := range ts { v.M() }", ""},
+		{"body-var", "for range ts { w := 1; fns = append(fns, func() int { return w }) }", ""},
+		{"assign", "var v T; for _, v = range ts { fns = append(fns, func() int { return v.x }) }", ""},
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := "package p\n\nimport (\n\t\"sort\"\n\t\"testing\"\n)\n\nvar _ = sort.Ints\n\ntype T struct{ x int }\n\nfunc (*T) M() {}\n\ntype S struct{ p *T }\n\nfunc use(T) {}\n\nfunc usePtr(*T) {}\n\nfunc apply(f func()) { f() }\n\nfunc f(tt *testing.T, ts []T, arrs [][1]int, ys [][]int, ch chan func() int) {\n\tvar (\n\t\tfns     []func() int\n\t\tptrs    []*int\n\t\tstructs []S\n\t)\n\t" + tc.body + "\n\t_, _, _, _ = tt, fns, ptrs, structs\n}\n"
+
+			file, err := parser.ParseFile(fset, tc.name+".go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Types:      make(map[ast.Expr]types.TypeAndValue),
+				Defs:       make(map[*ast.Ident]types.Object),
+				Uses:       make(map[*ast.Ident]types.Object),
+				Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			}
+			conf := &types.Config{Importer: imp}
+			if _, err := conf.Check("p", fset, []*ast.File{file}, info); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &RuleContext{Fset: fset, Info: info, file: file}
+			var got string
+			ast.Inspect(file, func(node ast.Node) bool {
+				if _, ok := matchLoopVarCapture(c, node); ok {
+					got = "escape"
+				} else if _, ok := possibleLoopVarCapture(c, node); ok && got == "" {
+					got = "maybe"
+				}
+				return true
+			})
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenericInference(t *testing.T) {
+	const src = `package p
+
+func Id[T any](x T) T { return x }
+
+func Apply[T any](f func(T) T, x T) T { return f(x) }
+
+func Pair[A, B any](a A, b B) {}
+
+type Getter[T any] interface{ Get() T }
+
+type intGetter struct{}
+
+func (intGetter) Get() int { return 0 }
+
+func Get[T any](g Getter[T]) T { return g.Get() }
+
+var (
+	a func(int) int          = Id
+	b                        = Apply(Id, 3)
+	c                        = Get(intGetter{})
+	d func(int, string)      = Pair[int]
+	e                        = Id(1)
+	f func(string) string    = Id[string]
+)
+`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(t.TempDir(), "p.go"), src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	pkg, err := new(types.Config).Check("p", fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s Scanner
+	if err := s.ensureHistory(); err != nil {
+		t.Fatal(err)
+	}
+	p := s.newPkgScanner(pkg, fset, info)
+	p.report = true
+	p.target = 20
+	if err := p.files([]*ast.File{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	for _, f := range p.findings {
+		if f.rule == "generic-inference" {
+			got = append(got, f.pos.Line)
+		}
+	}
+	if want := []int{18, 19, 20, 21}; !slices.Equal(got, want) {
+		t.Errorf("got findings on lines %v, want %v", got, want)
+	}
+}
+
+func TestComparableNonStrict(t *testing.T) {
+	cases := []struct {
+		name, expr string
+		want       bool
+	}{
+		{"int", "Eq[int]", false},
+		{"struct", "Eq[struct{ x int }]", false},
+		{"type-param", "func() { _ = Eq[K] }", false},
+		{"any-constraint", "Id[any]", false},
+		{"any", "Eq[any]", true},
+		{"error", "Eq(error(nil), nil)", true},
+		{"struct-with-interface", "Eq[struct{ x any }]", true},
+		{"array-of-interface", "Eq[[2]any]", true},
+		{"generic-type", "Set[any]{}", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := "package p\n\nfunc Eq[T comparable](a, b T) bool { return a == b }\n\nfunc Id[T any](x T) T { return x }\n\ntype Set[T comparable] map[T]bool\n\nfunc f[K comparable]() {\n\t_ = " + tc.expr + "\n}\n"
+
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "p.go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Types:     make(map[ast.Expr]types.TypeAndValue),
+				Uses:      make(map[*ast.Ident]types.Object),
+				Instances: make(map[*ast.Ident]types.Instance),
+			}
+			if _, err := new(types.Config).Check("p", fset, []*ast.File{file}, info); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &RuleContext{Fset: fset, Info: info, file: file}
+			var got bool
+			ast.Inspect(file, func(node ast.Node) bool {
+				if _, ok := matchComparableNonStrict(c, node); ok {
+					got = true
+				}
+				return true
+			})
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenericInstantiation(t *testing.T) {
+	const src = `package p
+
+import "slices"
+
+func Id[T any](x T) T { return x }
+
+var (
+	a = Id(1)
+	b = Id[int]
+	c = slices.Index([]int{1}, 1)
+	d = slices.Index[[]int, int]
+)
+`
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(t.TempDir(), "p.go"), src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	conf := &types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("p", fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s Scanner
+	if err := s.ensureHistory(); err != nil {
+		t.Fatal(err)
+	}
+	p := s.newPkgScanner(pkg, fset, info)
+	p.report = true
+	p.target = 17
+	if err := p.files([]*ast.File{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	for _, f := range p.findings {
+		if f.rule == "generic-instantiation" {
+			got = append(got, f.pos.Line)
+		}
+	}
+	if want := []int{8, 9, 10, 11}; !slices.Equal(got, want) {
+		t.Errorf("got findings on lines %v, want %v", got, want)
+	}
+}
+
+func TestServeMuxPattern(t *testing.T) {
+	cases := []struct {
+		name, stmt string
+		want       bool
+	}{
+		{"method", `http.HandleFunc("GET /items/", h)`, true},
+		{"host-method", `mux.Handle("POST example.com/items", handler)`, true},
+		{"wildcard", `mux.HandleFunc("/items/{id}", h)`, true},
+		{"rest-wildcard", `http.Handle("/files/{path...}", handler)`, true},
+		{"exact", `mux.Handle("/{$}", handler)`, true},
+		{"const", `mux.HandleFunc(prefix+"{id}", h)`, true},
+		{"paren", `(mux.HandleFunc)("GET /", h)`, true},
+		{"old", `mux.HandleFunc("/items/", h)`, false},
+		{"host", `http.Handle("example.com/", handler)`, false},
+		{"variable", `mux.HandleFunc(pattern, h)`, false},
+		{"other", `handleFunc("GET /items/{id}", h)`, false},
+	}
+
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := "package p\n\nimport \"net/http\"\n\nconst prefix = \"/items/\"\n\nfunc handleFunc(string, func(http.ResponseWriter, *http.Request)) {}\n\nfunc f(mux *http.ServeMux, handler http.Handler, h func(http.ResponseWriter, *http.Request), pattern string) {\n\t" + tc.stmt + "\n}\n"
+
+			file, err := parser.ParseFile(fset, tc.name+".go", src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &types.Info{
+				Types: make(map[ast.Expr]types.TypeAndValue),
+				Uses:  make(map[*ast.Ident]types.Object),
+			}
+			conf := &types.Config{Importer: imp}
+			if _, err := conf.Check("p", fset, []*ast.File{file}, info); err != nil {
+				t.Fatal(err)
+			}
+
+			c := &RuleContext{Fset: fset, Info: info, file: file}
+			var got bool
+			ast.Inspect(file, func(node ast.Node) bool {
+				if _, ok := matchServeMuxPattern(c, node); ok {
+					got = true
+				}
+				return true
+			})
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
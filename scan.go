@@ -1,18 +1,20 @@
 package mingo
 
 import (
+	"context"
 	"fmt"
-	"go/ast"
-	"go/token"
-	"go/types"
 	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/bobg/errors"
+	"golang.org/x/mod/semver"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -26,10 +28,96 @@ type Scanner struct {
 	Strict   bool   // with Check, require the go.mod declaration to be equal to the computed minimum
 	HistDir  string // find Go stdlib history in this directory (default: $GOROOT/api)
 
+	// ModHist maps module paths to directories containing API histories for those modules,
+	// for computing the minimum version of each one that the scanned code requires.
+	// Each directory contains files named for module versions (v1.2.0.txt etc.)
+	// in the same format as the go1.*.txt files in $GOROOT/api,
+	// each listing the API added in that version.
+	ModHist map[string]string
+
+	// DepVersions, if true, causes the scan to compute the minimum version of each required module
+	// that provides all the identifiers the scanned code uses from it,
+	// by checking the versions of the module in the local module cache.
+	// Results go in [ScanResult.ModResults] alongside those from ModHist.
+	DepVersions bool
+
+	// Verify, if true, cross-checks the scan with the type checker in [go/types].
+	// Each package must type-check at the version of Go computed for its language features
+	// and fail to type-check at the version below that
+	// (for Go 1.9 and later, the versions whose language changes the type checker enforces).
+	// Discrepancies are reported as [VerifyError]s.
+	Verify bool
+
+	// GoMod, if true, causes the scan to include the directives in the main module's go.mod file
+	// (and in the go.work file that applies to it, if any)
+	// that older go commands do not understand,
+	// like toolchain (Go 1.21) and godebug (Go 1.23).
+	GoMod bool
+
+	// Result is the result of the most recent scan to complete.
+	// With concurrent scans,
+	// use the values they return instead.
 	Result Result
 
-	h          *history
-	depScanner depScanner
+	mu          sync.Mutex // protects Result, and h, mh, and histWarning while they are being read
+	h           *history
+	mh          []*modHistory
+	histWarning Result // see [historyWarning]
+	depScanner  depScanner
+}
+
+// ScanResult is the outcome of a scan by [Scanner.ScanDirContext] or [Scanner.ScanPackagesContext].
+// It is a [Result] giving the lowest-numbered version of Go 1.x that can build the scanned code,
+// plus the minimum versions of other modules.
+type ScanResult struct {
+	Result
+
+	// ModResults maps the module paths in [Scanner.ModHist]
+	// (and, with [Scanner.DepVersions], the modules required in go.mod)
+	// to the minimum version of each one required by the scanned code.
+	// Modules that the scanned code does not use are absent.
+	ModResults map[string]ModResult
+
+	// Warnings are uses of features that stop working in some version of Go
+	// (given by each one's Version),
+	// like pull-style //go:linkname references to stdlib internals,
+	// which the linker restricts as of Go 1.23.
+	// They come from every package,
+	// including those not otherwise scanned
+	// once the max known Go version is reached,
+	// and are sorted by position.
+	// When the Go toolchain running the scan is newer than the API history,
+	// a warning saying so,
+	// with no position,
+	// comes first.
+	Warnings []Result
+}
+
+// Type scanState is the state of one scan.
+// Keeping it out of [Scanner] lets a Scanner run several scans at once.
+type scanState struct {
+	s          *Scanner
+	res        Result
+	modResults map[string]ModResult // see [ScanResult.ModResults]
+	refs       map[depRef]posResult // with DepVersions, references to identifiers in non-stdlib packages
+	warnings   []Result             // see [ScanResult.Warnings]
+}
+
+func (s *Scanner) newScanState() *scanState {
+	return &scanState{
+		s:          s,
+		res:        intResult(0),
+		modResults: make(map[string]ModResult),
+		refs:       make(map[depRef]posResult),
+	}
+}
+
+func (st *scanState) scanResult() *ScanResult {
+	warnings := st.warnings
+	if st.s.histWarning != nil {
+		warnings = append([]Result{st.s.histWarning}, warnings...)
+	}
+	return &ScanResult{Result: st.res, ModResults: st.modResults, Warnings: warnings}
 }
 
 // Mode is the minimum mode needed when using [packages.Load] to scan packages.
@@ -60,34 +148,74 @@ func (e LoadError) Unwrap() error {
 }
 
 // ScanDir scans the module in a directory to determine the lowest-numbered version of Go 1.x that can build it.
+// It is safe to call concurrently.
+// For the minimum versions of other modules and the warnings,
+// use [Scanner.ScanDirContext].
 func (s *Scanner) ScanDir(dir string) (Result, error) {
-	if err := s.ensureHistory(); err != nil {
+	res, err := s.ScanDirContext(context.Background(), dir)
+	if err != nil {
 		return nil, err
 	}
+	return res.Result, nil
+}
 
-	conf := &packages.Config{
-		Mode:  Mode,
-		Dir:   dir,
-		Tests: s.Tests,
+// ScanDirContext is like [Scanner.ScanDir]
+// but stops when ctx is canceled,
+// returning a [CanceledError],
+// and its result includes the minimum versions of other modules and the warnings.
+func (s *Scanner) ScanDirContext(ctx context.Context, dir string) (*ScanResult, error) {
+	if err := s.ensureHistory(); err != nil {
+		return nil, err
 	}
-	pkgs, err := packages.Load(conf, "./...")
+
+	pkgs, err := s.load(ctx, dir)
 	if err != nil {
-		return nil, errors.Wrap(err, "loading packages")
+		return nil, s.newScanState().canceled(ctx, err)
 	}
 
-	return s.ScanPackages(pkgs)
+	return s.ScanPackagesContext(ctx, pkgs)
+}
+
+func (s *Scanner) load(ctx context.Context, dir string) ([]*packages.Package, error) {
+	conf := &packages.Config{
+		Context: ctx,
+		Mode:    Mode,
+		Dir:     dir,
+		Tests:   s.Tests,
+	}
+	pkgs, err := packages.Load(conf, "./...")
+	return pkgs, errors.Wrap(err, "loading packages")
 }
 
 // ScanPackages scans the given packages to determine the lowest-numbered version of Go 1.x that can build them.
 // The packages must all be in the same module.
 // When using [packages.Load] to load the packages,
 // the value for [packages.Config.Mode] must be at least [Mode].
+//
+// The packages are scanned in parallel.
+// The result does not depend on the order in which they finish.
+// It is safe to call concurrently.
+// For the minimum versions of other modules and the warnings,
+// use [Scanner.ScanPackagesContext].
 func (s *Scanner) ScanPackages(pkgs []*packages.Package) (Result, error) {
-	if err := s.ensureHistory(); err != nil {
+	res, err := s.ScanPackagesContext(context.Background(), pkgs)
+	if err != nil {
 		return nil, err
 	}
+	return res.Result, nil
+}
 
-	s.Result = intResult(0)
+// ScanPackagesContext is like [Scanner.ScanPackages]
+// but stops when ctx is canceled,
+// returning a [CanceledError],
+// and its result includes the minimum versions of other modules and the warnings.
+// Cancellation reaches the scan of each package's syntax trees,
+// the download of each dependency's go.mod file (with [Scanner.Deps]),
+// and the search for each dependency's minimum version (with [Scanner.DepVersions]).
+func (s *Scanner) ScanPackagesContext(ctx context.Context, pkgs []*packages.Package) (*ScanResult, error) {
+	if err := s.ensureHistory(); err != nil {
+		return nil, err
+	}
 
 	// Check for loading errors.
 	var err error
@@ -104,86 +232,200 @@ func (s *Scanner) ScanPackages(pkgs []*packages.Package) (Result, error) {
 		if pkg.Module == nil {
 			return nil, fmt.Errorf("package %s has no module", pkg.PkgPath)
 		}
-
 		if i > 0 && pkg.Module.Path != pkgs[0].Module.Path {
 			return nil, fmt.Errorf("multiple modules: %s and %s", pkgs[0].Module.Path, pkg.Module.Path)
 		}
+	}
 
-		if err := s.scanPackage(pkg); err != nil {
-			return nil, errors.Wrapf(err, "scanning package %s", pkg.PkgPath)
+	st := s.newScanState()
+
+	if err := st.scanPackages(ctx, pkgs); err != nil {
+		return nil, st.canceled(ctx, err)
+	}
+
+	if s.GoMod && len(pkgs) > 0 && pkgs[0].Module.GoMod != "" {
+		if err := st.scanGoMod(pkgs[0].Module.GoMod); err != nil {
+			return nil, errors.Wrap(err, "scanning go.mod")
 		}
-		if s.isMax() {
-			break
+	}
+
+	if s.Deps && len(pkgs) > 0 {
+		if err := st.scanDeps(ctx, pkgs[0].Module.GoMod); err != nil {
+			return nil, st.canceled(ctx, errors.Wrap(err, "scanning dependencies"))
 		}
 	}
 
-	if s.Deps && len(pkgs) > 0 && pkgs[0].Module != nil {
-		if err := s.scanDeps(pkgs[0].Module.GoMod); err != nil {
-			return nil, errors.Wrap(err, "scanning dependencies")
+	if s.DepVersions && len(pkgs) > 0 {
+		if err := st.depVersions(ctx, pkgs[0].Module.GoMod); err != nil {
+			return nil, st.canceled(ctx, errors.Wrap(err, "computing dependency versions"))
 		}
 	}
 
 	if s.Check && len(pkgs) > 0 {
-		var declared int
-		parts := strings.SplitN(pkgs[0].Module.GoVersion, ".", 3)
-		if len(parts) < 2 {
-			return nil, fmt.Errorf("go.mod has invalid go version %s", pkgs[0].Module.GoVersion)
-		}
-		declared, err := strconv.Atoi(parts[1])
+		declared, err := parseGoVersion(pkgs[0].Module.GoVersion)
 		if err != nil {
-			return nil, fmt.Errorf("go.mod has invalid go version %s", pkgs[0].Module.GoVersion)
+			return nil, errors.Wrap(err, "in go.mod")
 		}
 		if s.Strict {
-			if s.Result.Version() != declared {
+			if st.res.Version() != declared {
 				return nil, VersionError{
-					Computed: s.Result,
+					Computed: st.res,
 					Declared: declared,
 				}
 			}
 		} else {
-			if s.Result.Version() > declared {
+			if st.res.Version() > declared {
 				return nil, VersionError{
-					Computed: s.Result,
+					Computed: st.res,
 					Declared: declared,
 				}
 			}
 		}
 	}
 
-	return s.Result, nil
+	s.mu.Lock()
+	s.Result = st.res
+	s.mu.Unlock()
+
+	return st.scanResult(), nil
+}
+
+// CanceledError is the error returned by [Scanner.ScanDirContext] and [Scanner.ScanPackagesContext]
+// when the context is canceled.
+// It holds the results of the scan so far,
+// which are lower bounds on the complete results.
+type CanceledError struct {
+	Err     error       // The context's error: [context.Canceled] or [context.DeadlineExceeded].
+	Partial *ScanResult // The results so far.
 }
 
-func (s *Scanner) reset() {
-	s.Result = intResult(0)
+func (e CanceledError) Error() string {
+	return fmt.Sprintf("scan canceled (partial result %s): %s", e.Partial, e.Err)
 }
 
-func (s *Scanner) scanPackage(pkg *packages.Package) error {
-	return s.scanPackageHelper(pkg.PkgPath, pkg.Fset, pkg.TypesInfo, pkg.Syntax)
+func (e CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// Method canceled turns err into a [CanceledError] if ctx is done.
+// Otherwise it returns err unchanged.
+func (st *scanState) canceled(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	return CanceledError{
+		Err:     ctx.Err(),
+		Partial: st.scanResult(),
+	}
 }
 
-func (s *Scanner) scanPackageHelper(pkgpath string, fset *token.FileSet, info *types.Info, files []*ast.File) error {
-	p := pkgScanner{
-		s:       s,
-		pkgpath: pkgpath,
-		fset:    fset,
-		info:    info,
+// Method scanPackages scans pkgs in parallel,
+// one [pkgScanner] per package,
+// then merges their results in order,
+// so that the outcome does not depend on scheduling.
+//
+// Once some package reaches the max known Go version,
+// the packages after it are not scanned fully:
+// the merge would take nothing from them but their warnings,
+// so only those are collected.
+// The packages before it are still scanned,
+// in case one of them reaches the max too and so comes first in the merge.
+//
+// With [Scanner.Verify],
+// each package is also cross-checked with the type checker,
+// and the error is the [VerifyError]s, if any.
+func (st *scanState) scanPackages(ctx context.Context, pkgs []*packages.Package) error {
+	var (
+		scanners   = make([]*pkgScanner, len(pkgs))
+		errs       = make([]error, len(pkgs))
+		verifyErrs = make([]error, len(pkgs))
+		next       atomic.Int64 // the index of the next package to scan
+		firstMax   atomic.Int64 // the index of the first package known to reach the max
+		wg         sync.WaitGroup
+	)
+	firstMax.Store(int64(len(pkgs)))
+
+	for range min(runtime.GOMAXPROCS(0), len(pkgs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(len(pkgs)) {
+					return
+				}
+				pkg := pkgs[i]
+				p := st.s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+				p.ctx = ctx
+
+				if i > firstMax.Load() {
+					errs[i] = errors.Wrapf(p.warnFiles(pkg.Syntax), "scanning package %s", pkg.PkgPath)
+					scanners[i] = p
+					continue
+				}
+				errs[i] = errors.Wrapf(p.files(pkg.Syntax), "scanning package %s", pkg.PkgPath)
+				scanners[i] = p
+				if errs[i] == nil && st.s.Verify {
+					verifyErrs[i] = p.verify(pkg.Syntax)
+				}
+
+				if p.isMax() {
+					for {
+						prev := firstMax.Load()
+						if i >= prev || firstMax.CompareAndSwap(prev, i) {
+							break
+						}
+					}
+				}
+			}
+		}()
 	}
+	wg.Wait()
 
-	for _, file := range files {
-		filename := p.fset.Position(file.Pos()).Filename
-		isInCache, err := isCacheFile(filename)
+	// Keep what was found, even if the scan was canceled partway through.
+	for _, p := range scanners {
+		if p != nil {
+			st.merge(p)
+		}
+	}
+	slices.SortStableFunc(st.warnings, func(a, b Result) int {
+		return comparePositions(a.(posResult).pos, b.(posResult).pos)
+	})
+	for _, err := range errs {
 		if err != nil {
-			return errors.Wrapf(err, "checking whether %s is in GOCACHE", filename)
+			return err
 		}
-		if isInCache {
-			continue
+	}
+	return errors.Join(verifyErrs...)
+}
+
+// Method merge adds the results of a package scan to st.
+// Ties go to the result merged first.
+func (st *scanState) merge(p *pkgScanner) {
+	if p.res.Version() > st.res.Version() {
+		st.res = p.res
+	}
+	for modpath, r := range p.modResults {
+		if prev, ok := st.modResults[modpath]; !ok || semver.Compare(r.Version, prev.Version) > 0 {
+			st.modResults[modpath] = r
 		}
-		if isMax, err := p.file(file); err != nil || isMax {
-			return errors.Wrapf(err, "scanning file %s", filename)
+	}
+	for ref, res := range p.refs {
+		if _, ok := st.refs[ref]; !ok {
+			st.refs[ref] = res
 		}
 	}
+	for _, w := range p.warnings {
+		st.warnings = append(st.warnings, w)
+	}
+}
 
-	return nil
+func (st *scanState) result(r Result) bool {
+	if r.Version() > st.res.Version() {
+		st.res = r
+		st.s.verbosef("%s", r)
+	}
+	return st.s.isMax(st.res.Version())
 }
 
 func (s *Scanner) lookup(pkgpath, name, typ string) int {
@@ -200,17 +442,30 @@ func (s *Scanner) verbosef(format string, args ...any) {
 	}
 }
 
-func (s *Scanner) result(r Result) bool {
-	if r.Version() > s.Result.Version() {
-		s.Result = r
-		s.verbosef("%s", r)
+// Function parseGoVersion parses a Go version like 1.21, 1.21.0, or go1.21rc1,
+// returning the minor version (21 in these examples).
+func parseGoVersion(v string) (int, error) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "go"), ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, fmt.Errorf("invalid go version %s", v)
+	}
+	minor := parts[1]
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i] // e.g. 1.21rc1
 	}
-	return s.isMax()
+	result, err := strconv.Atoi(minor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid go version %s", v)
+	}
+	return result, nil
 }
 
-var goverRegex = regexp.MustCompile(`^go(\d+)\.(\d+)`)
+var goverRegex = regexp.MustCompile(`^(?:devel )?go(\d+)\.(\d+)`)
 
 func (s *Scanner) ensureHistory() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.h != nil {
 		return nil
 	}
@@ -221,6 +476,14 @@ func (s *Scanner) ensureHistory() error {
 
 	s.h = h
 
+	for modpath, dir := range s.ModHist {
+		m, err := readModHist(modpath, dir)
+		if err != nil {
+			return errors.Wrapf(err, "reading API history of %s", modpath)
+		}
+		s.mh = append(s.mh, m)
+	}
+
 	gover := runtime.Version()
 	m := goverRegex.FindStringSubmatch(gover)
 	if len(m) == 0 {
@@ -239,16 +502,27 @@ func (s *Scanner) ensureHistory() error {
 	if err != nil {
 		return errors.Wrapf(err, "parsing minor version from runtime version %s", gover)
 	}
-	if minor != s.h.max {
+	switch {
+	case minor > s.h.max:
+		// A tip or release-candidate toolchain can be newer than the history it ships with.
+		s.histWarning = historyWarning{runtime: minor, max: s.h.max, histDir: s.HistDir}
+
+	case minor < s.h.max && !(s.h.next > 0 && minor == s.h.next-1):
 		return fmt.Errorf("runtime Go version 1.%d does not match history max 1.%d (reading from HistDir %q)", minor, s.h.max, s.HistDir)
 	}
 
 	return nil
 }
 
-// Prereq: e.ensureHistory has been called.
-func (s *Scanner) isMax() bool {
-	return s.Result.Version() >= s.h.max
+// Method isMax tells whether v is the max known Go version,
+// so that scanning can stop.
+// Prereq: s.ensureHistory has been called.
+func (s *Scanner) isMax(v int) bool {
+	if len(s.mh) > 0 || s.DepVersions {
+		// Keep going to find the minimum version of each non-stdlib module.
+		return false
+	}
+	return v >= s.h.max
 }
 
 func isCacheFile(filename string) (bool, error) {
@@ -1,7 +1,13 @@
 package mingo
 
 import (
+	"context"
+	"go/parser"
+	"go/token"
+	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 
 	"github.com/bobg/errors"
@@ -98,3 +104,193 @@ func TestIsWithinDir(t *testing.T) {
 		})
 	}
 }
+
+func TestGoBuildVersion(t *testing.T) {
+	cases := []struct {
+		src  string
+		want int
+	}{
+		{src: "package p", want: 0},
+		{src: "//go:build go1.21\n\npackage p", want: 21},
+		{src: "//go:build linux && go1.21\n\npackage p", want: 21},
+		{src: "//go:build go1.20 || go1.22\n\npackage p", want: 20},
+		{src: "//go:build !go1.21\n\npackage p", want: 0},
+		{src: "// Copyright.\n\n//go:build go1.18\n\npackage p", want: 18},
+		{src: "package p\n\n//go:build go1.21", want: 0},
+	}
+	for _, tc := range cases {
+		file, err := parser.ParseFile(token.NewFileSet(), "", tc.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := goBuildVersion(file); got != tc.want {
+			t.Errorf("%q: got %d, want %d", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestScanCanceled(t *testing.T) {
+	t.Run("load", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var s Scanner
+		_, err := s.ScanDirContext(ctx, "testdata/lsp")
+		var cerr CanceledError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("got error %v, want CanceledError", err)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("deps", func(t *testing.T) {
+		conf := &packages.Config{Mode: Mode, Dir: "testdata/lsp"}
+		pkgs, err := packages.Load(conf, "./...")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pkg := range pkgs {
+			pkg.Module.GoMod = "testdata/go.mod" // which has a require directive
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		s := Scanner{
+			Deps:       true,
+			depScanner: blockingDepScanner{cancel: cancel},
+		}
+		_, err = s.ScanPackagesContext(ctx, pkgs)
+		var cerr CanceledError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("got error %v, want CanceledError", err)
+		}
+		if cerr.Partial.Version() != 21 {
+			t.Errorf("got partial result %s, want 21", cerr.Partial)
+		}
+	})
+}
+
+// Type blockingDepScanner simulates a hung module proxy.
+type blockingDepScanner struct {
+	cancel context.CancelFunc
+}
+
+func (s blockingDepScanner) scan(ctx context.Context, modpath, version string) (modDownload, error) {
+	s.cancel()
+	<-ctx.Done()
+	return modDownload{}, ctx.Err()
+}
+
+func TestScanConcurrent(t *testing.T) {
+	var (
+		s       Scanner
+		wg      sync.WaitGroup
+		results = make([]Result, 4)
+		errs    = make([]error, len(results))
+	)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.ScanDir("testdata/watch")
+		}()
+	}
+	wg.Wait()
+
+	for i, res := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if res.Version() != 21 {
+			t.Errorf("scan %d: got version %d, want 21", i, res.Version())
+		}
+		if got, want := res.String(), results[0].String(); got != want {
+			t.Errorf("scan %d: got %q, want %q", i, got, want)
+		}
+	}
+
+	// The Result field holds the result of the last scan to complete.
+	if s.Result == nil || s.Result.String() != results[0].String() {
+		t.Errorf("got Result field %v, want %q", s.Result, results[0])
+	}
+}
+
+// Warnings come from every package and file,
+// even those after the max known Go version is reached,
+// in an order that does not depend on the order of the packages.
+func TestScanWarnings(t *testing.T) {
+	histdir := t.TempDir()
+	hist := map[string]string{
+		"go1.txt":    "",
+		"go1.18.txt": "pkg strings, func Cut(string, string) (string, string, bool)\n",
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+
+		// In package a, a.go reaches the max before z.go is scanned.
+		"a/a.go": "package a\n\nimport \"strings\"\n\nfunc F(s string) (string, string, bool) { return strings.Cut(s, \",\") }\n",
+		"a/z.go": "package a\n\nimport _ \"unsafe\"\n\n//go:linkname nanotime runtime.nanotime\nfunc nanotime() int64\n",
+
+		// Package b comes after package a.
+		"b/b.go": "package b\n\nimport _ \"unsafe\"\n\n//go:linkname fastrand runtime.fastrand\nfunc fastrand() uint32\n",
+	}
+	for name, contents := range hist {
+		if err := os.WriteFile(filepath.Join(histdir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := Scanner{HistDir: histdir}
+	res, err := s.ScanDirContext(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version() != 18 {
+		t.Errorf("got version %d, want 18 [%s]", res.Version(), res)
+	}
+
+	// The API history stops at Go 1.18,
+	// older than the toolchain running the test,
+	// so a warning about that comes first.
+	if len(res.Warnings) == 0 {
+		t.Fatal("got no warnings")
+	}
+	if _, ok := res.Warnings[0].(historyWarning); !ok {
+		t.Errorf("got first warning %s, want the history warning", res.Warnings[0])
+	}
+
+	var got []string
+	for _, w := range res.Warnings[1:] {
+		got = append(got, filepath.Base(w.(posResult).pos.Filename))
+	}
+	if want := []string{"z.go", "b.go"}; !slices.Equal(got, want) {
+		t.Errorf("got warnings in %v, want %v", got, want)
+	}
+
+	// The warnings do not depend on the order of the packages.
+	pkgs, err := packages.Load(&packages.Config{Mode: Mode, Dir: dir}, "./...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Reverse(pkgs)
+	res2, err := s.ScanPackagesContext(context.Background(), pkgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.EqualFunc(res.Warnings, res2.Warnings, func(a, b Result) bool { return a.String() == b.String() }) {
+		t.Errorf("got warnings %v with the packages reversed, want %v", res2.Warnings, res.Warnings)
+	}
+}
@@ -0,0 +1,50 @@
+package mingo
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// The functions and methods that register net/http.ServeMux patterns,
+// in the form of the keys of [formatFuncs].
+var serveMuxFuncs = map[string]bool{
+	"net/http.Handle":              true,
+	"net/http.HandleFunc":          true,
+	"net/http.ServeMux.Handle":     true,
+	"net/http.ServeMux.HandleFunc": true,
+}
+
+// As of Go 1.22,
+// net/http.ServeMux patterns may begin with a method (as in "GET /items/")
+// and contain wildcards (as in "/items/{id}", "/files/{path...}", or "/{$}").
+// Earlier versions take them literally,
+// so such a pattern compiles with any version of Go
+// but routes differently before 1.22.
+// This checks the constant pattern arguments of
+// http.Handle, http.HandleFunc, and the ServeMux methods of the same names.
+func matchServeMuxPattern(c *RuleContext, node ast.Node) (token.Pos, bool) {
+	call, ok := node.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return token.NoPos, false
+	}
+	if name, ok := calleeName(c, call.Fun); !ok || !serveMuxFuncs[name] {
+		return token.NoPos, false
+	}
+	pattern, ok := constantString(c, call.Args[0])
+	if !ok || !isNewServeMuxPattern(pattern) {
+		return token.NoPos, false
+	}
+	return call.Pos(), true
+}
+
+// Function isNewServeMuxPattern tells whether pattern uses the routing syntax of Go 1.22:
+// a method before the host and path,
+// or a wildcard in braces.
+func isNewServeMuxPattern(pattern string) bool {
+	if i := strings.IndexAny(pattern, " \t"); i > 0 && !strings.Contains(pattern[:i], "/") {
+		return true
+	}
+	open := strings.Index(pattern, "{")
+	return open >= 0 && strings.Contains(pattern[open:], "}")
+}
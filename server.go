@@ -0,0 +1,502 @@
+package mingo
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bobg/errors"
+)
+
+// Server is an [http.Handler] for a JSON HTTP API to mingo.
+//
+// It handles POST requests to /scan.
+// A request with Content-Type application/json has a [ScanRequest] body
+// naming a module directory or supplying source files.
+// A request with Content-Type application/zip has a zipped module as its body,
+// and takes the options in a ScanRequest as query parameters
+// (tests, deps, check, strict, and target).
+// The response is a [ScanResponse].
+//
+// Each request gets its own [Scanner],
+// so requests can run concurrently.
+// A scan stops when its request's context is canceled
+// (e.g. because the client went away).
+type Server struct {
+	// Root is the directory under which ScanRequest.Dir is resolved.
+	// Requests for directories outside it are refused.
+	// If Root is empty, directory requests are refused altogether.
+	Root string
+
+	HistDir string // Find Go stdlib history in this directory (default: $GOROOT/api).
+	Verbose bool   // Log to stderr.
+
+	// MaxUpload is the largest request body accepted, in bytes
+	// (default: 64 MiB).
+	MaxUpload int64
+
+	// MaxUnzipped is the most that a zipped module may extract to, in bytes
+	// (default: 500 MiB, the go command's limit for module zip files).
+	MaxUnzipped int64
+
+	// MaxZipFiles is the most entries a zipped module may have
+	// (default: 10,000).
+	MaxZipFiles int
+
+	once sync.Once
+	h    *history
+	err  error
+}
+
+// ScanRequest is the JSON body of a request to [Server].
+// Exactly one of Dir and Files must be given.
+type ScanRequest struct {
+	// Dir is a module directory, relative to [Server.Root].
+	Dir string `json:"dir,omitempty"`
+
+	// Files maps filenames (slash-separated, relative to the module root) to their contents.
+	// If there is no go.mod file,
+	// one is supplied with a go directive for the newest known version of Go.
+	Files map[string]string `json:"files,omitempty"`
+
+	Tests  bool   `json:"tests,omitempty"`  // Include *_test.go files.
+	Deps   string `json:"deps,omitempty"`   // Which dependencies to scan: all, direct, or none (the default).
+	Check  bool   `json:"check,omitempty"`  // Check the go directive in go.mod against the result.
+	Strict bool   `json:"strict,omitempty"` // With Check, require go.mod to declare exactly the result.
+
+	// Target is the minor version of Go 1.x above which to report findings
+	// (default: the version in go.mod).
+	Target int `json:"target,omitempty"`
+}
+
+// ScanResponse is the JSON response from [Server].
+type ScanResponse struct {
+	Version  int            `json:"version"`          // The minimum minor version of Go 1.x.
+	Reason   string         `json:"reason,omitempty"` // Why, if Version is nonzero.
+	Findings []ScanFinding  `json:"findings"`         // Uses of features above the target.
+	Check    *CheckResponse `json:"check,omitempty"`  // With ScanRequest.Check.
+	Error    string         `json:"error,omitempty"`  // If the scan failed, why.
+}
+
+// ScanFinding is a use of a language feature or stdlib identifier in a [ScanResponse].
+type ScanFinding struct {
+	Pos         string `json:"pos"` // Filename (relative to the module root), line, and column.
+	Version     int    `json:"version"`
+	Desc        string `json:"desc"`
+	Rule        string `json:"rule,omitempty"` // The ID of the language-feature rule, if any.
+	Provisional bool   `json:"provisional,omitempty"`
+}
+
+// CheckResponse is the result of checking go.mod in a [ScanResponse].
+type CheckResponse struct {
+	Declared int  `json:"declared"` // The minor version of Go 1.x declared in go.mod.
+	OK       bool `json:"ok"`
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/scan" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		srv.respond(w, http.StatusMethodNotAllowed, ScanResponse{Error: "method not allowed"})
+		return
+	}
+
+	srv.once.Do(func() { srv.h, srv.err = readHist(srv.HistDir) })
+	if srv.err != nil {
+		srv.respond(w, http.StatusInternalServerError, ScanResponse{Error: srv.err.Error()})
+		return
+	}
+
+	maxUpload := srv.MaxUpload
+	if maxUpload <= 0 {
+		maxUpload = 64 << 20
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, maxUpload)
+
+	status, resp := srv.scan(req)
+	srv.respond(w, status, resp)
+}
+
+func (srv *Server) respond(w http.ResponseWriter, status int, resp ScanResponse) {
+	if resp.Findings == nil {
+		resp.Findings = []ScanFinding{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		srv.logf("writing response: %s", err)
+	}
+}
+
+// Type requestError is an error in the request (as opposed to in the scan).
+type requestError struct {
+	err error
+}
+
+func (e requestError) Error() string { return e.err.Error() }
+func (e requestError) Unwrap() error { return e.err }
+
+func badRequest(format string, args ...any) error {
+	return requestError{err: fmt.Errorf(format, args...)}
+}
+
+func (srv *Server) scan(req *http.Request) (int, ScanResponse) {
+	sreq, dir, cleanup, err := srv.parseRequest(req)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		return errorStatus(err), ScanResponse{Error: err.Error()}
+	}
+
+	s := &Scanner{
+		Deps:     sreq.Deps == "all" || sreq.Deps == "direct",
+		Indirect: sreq.Deps == "all",
+		Tests:    sreq.Tests,
+		Check:    sreq.Check || sreq.Strict,
+		Strict:   sreq.Strict,
+		HistDir:  srv.HistDir,
+		Verbose:  srv.Verbose,
+		h:        srv.h,
+	}
+
+	ctx := req.Context()
+	pkgs, err := s.load(ctx, dir)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		err = s.newScanState().canceled(ctx, err)
+		return errorStatus(err), ScanResponse{Error: err.Error()}
+	}
+	if len(pkgs) == 0 {
+		return http.StatusUnprocessableEntity, ScanResponse{Error: "no packages"}
+	}
+
+	var (
+		resp   ScanResponse
+		result Result
+		verr   VersionError
+	)
+
+	scanned, err := s.ScanPackagesContext(ctx, pkgs)
+	switch {
+	case errors.As(err, &verr):
+		result = verr.Computed
+		resp.Check = &CheckResponse{Declared: verr.Declared}
+	case err != nil:
+		return errorStatus(err), ScanResponse{Error: err.Error()}
+	default:
+		result = scanned
+	}
+	if err == nil && s.Check {
+		declared, err := parseGoVersion(pkgs[0].Module.GoVersion)
+		if err != nil {
+			return http.StatusUnprocessableEntity, ScanResponse{Error: errors.Wrap(err, "in go.mod").Error()}
+		}
+		resp.Check = &CheckResponse{Declared: declared, OK: true}
+	}
+
+	resp.Version = result.Version()
+	if resp.Version > 0 {
+		resp.Reason = result.String()
+	}
+
+	// Collect the findings above the target.
+	target := sreq.Target
+	if target == 0 && pkgs[0].Module.GoVersion != "" {
+		if target, err = parseGoVersion(pkgs[0].Module.GoVersion); err != nil {
+			return http.StatusUnprocessableEntity, ScanResponse{Error: errors.Wrap(err, "in go.mod").Error()}
+		}
+	}
+	seen := make(map[string]bool) // packages with tests share files
+	for _, pkg := range pkgs {
+		p := s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+		p.report = true
+		p.target = target
+		p.ctx = ctx
+		if err := p.files(pkg.Syntax); err != nil {
+			if ctx.Err() != nil {
+				err = CanceledError{Err: ctx.Err(), Partial: &ScanResult{Result: result}}
+				return errorStatus(err), ScanResponse{Error: err.Error()}
+			}
+			return http.StatusInternalServerError, ScanResponse{Error: errors.Wrapf(err, "scanning package %s", pkg.PkgPath).Error()}
+		}
+		for _, f := range p.findings {
+			pos := f.pos
+			if rel, err := filepath.Rel(pkgs[0].Module.Dir, pos.Filename); err == nil {
+				pos.Filename = filepath.ToSlash(rel)
+			}
+			finding := ScanFinding{
+				Pos:         pos.String(),
+				Version:     f.version,
+				Desc:        f.desc,
+				Rule:        f.rule,
+				Provisional: f.provisional,
+			}
+			if seen[finding.Pos+finding.Desc] {
+				continue
+			}
+			seen[finding.Pos+finding.Desc] = true
+			resp.Findings = append(resp.Findings, finding)
+		}
+	}
+
+	return http.StatusOK, resp
+}
+
+func errorStatus(err error) int {
+	var (
+		rerr  requestError
+		lerr  LoadError
+		mberr *http.MaxBytesError
+		zerr  zipLimitError
+		cerr  CanceledError
+	)
+	switch {
+	case errors.As(err, &cerr):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &mberr), errors.As(err, &zerr):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &rerr):
+		return http.StatusBadRequest
+	case errors.As(err, &lerr):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// Method parseRequest parses req
+// and returns the module directory to scan,
+// plus a function to remove it if it is temporary.
+func (srv *Server) parseRequest(req *http.Request) (*ScanRequest, string, func(), error) {
+	mediatype, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", nil, badRequest("parsing Content-Type: %s", err)
+	}
+
+	switch mediatype {
+	case "application/json":
+		sreq := new(ScanRequest)
+		if err := json.NewDecoder(req.Body).Decode(sreq); err != nil {
+			return nil, "", nil, requestError{err: errors.Wrap(err, "decoding request")}
+		}
+		if err := checkDeps(sreq.Deps); err != nil {
+			return nil, "", nil, err
+		}
+
+		switch {
+		case sreq.Dir != "" && len(sreq.Files) > 0:
+			return nil, "", nil, badRequest("request has both dir and files")
+
+		case sreq.Dir != "":
+			dir, err := srv.resolveDir(sreq.Dir)
+			return sreq, dir, nil, err
+
+		case len(sreq.Files) > 0:
+			dir, err := os.MkdirTemp("", "mingo")
+			if err != nil {
+				return nil, "", nil, err
+			}
+			cleanup := func() { os.RemoveAll(dir) }
+			return sreq, dir, cleanup, srv.writeFiles(dir, sreq.Files)
+		}
+		return nil, "", nil, badRequest("request has neither dir nor files")
+
+	case "application/zip":
+		q := req.URL.Query()
+		sreq := &ScanRequest{
+			Tests:  q.Get("tests") != "",
+			Deps:   q.Get("deps"),
+			Check:  q.Get("check") != "",
+			Strict: q.Get("strict") != "",
+		}
+		if t := q.Get("target"); t != "" {
+			if sreq.Target, err = strconv.Atoi(strings.TrimPrefix(t, "1.")); err != nil {
+				return nil, "", nil, badRequest("invalid target %q", t)
+			}
+		}
+		if err := checkDeps(sreq.Deps); err != nil {
+			return nil, "", nil, err
+		}
+
+		dir, err := os.MkdirTemp("", "mingo")
+		if err != nil {
+			return nil, "", nil, err
+		}
+		cleanup := func() { os.RemoveAll(dir) }
+		maxUnzipped, maxFiles := srv.MaxUnzipped, srv.MaxZipFiles
+		if maxUnzipped <= 0 {
+			maxUnzipped = 500 << 20
+		}
+		if maxFiles <= 0 {
+			maxFiles = 10000
+		}
+		moddir, err := unzipModule(req.Body, dir, maxUnzipped, maxFiles)
+		return sreq, moddir, cleanup, err
+	}
+
+	return nil, "", nil, badRequest("unsupported Content-Type %s (want application/json or application/zip)", mediatype)
+}
+
+func checkDeps(deps string) error {
+	switch deps {
+	case "", "all", "direct", "none":
+		return nil
+	}
+	return badRequest("invalid deps value %q (should be all, direct, or none)", deps)
+}
+
+func (srv *Server) resolveDir(dir string) (string, error) {
+	if srv.Root == "" {
+		return "", badRequest("directory requests are not enabled")
+	}
+	root, err := filepath.Abs(srv.Root)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(filepath.FromSlash(dir)) {
+		return "", badRequest("directory %s is not within the server root", dir)
+	}
+	return filepath.Join(root, filepath.FromSlash(dir)), nil
+}
+
+func (srv *Server) writeFiles(dir string, files map[string]string) error {
+	for name, content := range files {
+		filename := filepath.FromSlash(name)
+		if !filepath.IsLocal(filename) {
+			return badRequest("invalid filename %s", name)
+		}
+		filename = filepath.Join(dir, filename)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	if _, ok := files["go.mod"]; ok {
+		return nil
+	}
+	gomod := fmt.Sprintf("module scan\n\ngo 1.%d\n", srv.h.max)
+	return os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644)
+}
+
+// Function unzipModule extracts a zipped module into dir,
+// returning the directory of its (shallowest) go.mod file.
+// This handles both plain zip files of a module tree
+// and module zip files as served by module proxies
+// (whose paths begin with module@version/).
+//
+// The zip file may have at most maxFiles entries
+// extracting to at most maxBytes in all.
+// The sizes in the zip file's headers are not trusted:
+// the count is of the bytes actually extracted.
+func unzipModule(r io.Reader, dir string, maxBytes int64, maxFiles int) (string, error) {
+	// The zip format needs random access, so save the upload first.
+	tmp, err := os.CreateTemp(dir, "upload*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return "", requestError{err: errors.Wrap(err, "reading upload")}
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return "", requestError{err: errors.Wrap(err, "reading zip file")}
+	}
+	if len(zr.File) > maxFiles {
+		return "", zipLimitError{fmt.Sprintf("more than %d entries", maxFiles)}
+	}
+
+	dest := filepath.Join(dir, "mod")
+	moddir := ""
+	remaining := maxBytes
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := filepath.FromSlash(f.Name)
+		if !filepath.IsLocal(name) {
+			return "", badRequest("invalid filename %s in zip file", f.Name)
+		}
+		filename := filepath.Join(dest, name)
+		n, err := extractFile(f, filename, remaining)
+		if err != nil {
+			return "", errors.Wrapf(err, "extracting %s", f.Name)
+		}
+		remaining -= n
+		if filepath.Base(filename) == "go.mod" {
+			if d := filepath.Dir(filename); moddir == "" || len(d) < len(moddir) {
+				moddir = d
+			}
+		}
+	}
+	if moddir == "" {
+		return "", badRequest("no go.mod in zip file")
+	}
+	return moddir, nil
+}
+
+// Function extractFile extracts f to filename,
+// returning the number of bytes written.
+// It fails with a [zipLimitError] if that would be more than limit.
+func extractFile(f *zip.File, filename string, limit int64) (int64, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return 0, zipLimitError{"too large when extracted"}
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return 0, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, fs.FileMode(0644))
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, io.LimitReader(rc, limit+1))
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	if n > limit {
+		out.Close()
+		return 0, zipLimitError{"too large when extracted"}
+	}
+	return n, out.Close()
+}
+
+// Type zipLimitError is the error for a zipped module
+// that exceeds [Server.MaxUnzipped] or [Server.MaxZipFiles].
+type zipLimitError struct {
+	msg string
+}
+
+func (e zipLimitError) Error() string { return "zip file " + e.msg }
+
+func (srv *Server) logf(format string, args ...any) {
+	if !srv.Verbose {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+	if !strings.HasSuffix(format, "\n") {
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -0,0 +1,194 @@
+package mingo
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	srv := &Server{Root: "testdata"}
+
+	post := func(ctx context.Context, contentType, query string, body []byte) (int, ScanResponse) {
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/scan"+query, bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		var resp ScanResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decoding response %q: %s", rec.Body.String(), err)
+		}
+		return rec.Code, resp
+	}
+	postJSON := func(sreq ScanRequest) (int, ScanResponse) {
+		body, err := json.Marshal(sreq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return post(context.Background(), "application/json", "", body)
+	}
+	hasFinding := func(resp ScanResponse, pos, desc string) bool {
+		for _, f := range resp.Findings {
+			if f.Pos == pos && f.Desc == desc {
+				return true
+			}
+		}
+		return false
+	}
+
+	src, err := os.ReadFile("testdata/lsp/lsp.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("files", func(t *testing.T) {
+		status, resp := postJSON(ScanRequest{
+			Files: map[string]string{
+				"go.mod":     "module example.com/files\n\ngo 1.20\n",
+				"sub/sub.go": string(src),
+			},
+			Check: true,
+		})
+		if status != http.StatusOK {
+			t.Fatalf("got status %d [%s]", status, resp.Error)
+		}
+		if resp.Version != 21 {
+			t.Errorf("got version %d, want 21", resp.Version)
+		}
+		if resp.Check == nil || resp.Check.OK || resp.Check.Declared != 20 {
+			t.Errorf("got check %+v, want declared 20 and not ok", resp.Check)
+		}
+		if !hasFinding(resp, "sub/sub.go:9:2", `"slices".SortFunc`) {
+			t.Errorf("findings %+v do not include slices.SortFunc", resp.Findings)
+		}
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		status, resp := postJSON(ScanRequest{Dir: "lsp", Target: 18})
+		if status != http.StatusOK {
+			t.Fatalf("got status %d [%s]", status, resp.Error)
+		}
+		if resp.Version != 21 {
+			t.Errorf("got version %d, want 21", resp.Version)
+		}
+		if resp.Check != nil {
+			t.Errorf("got check %+v without asking", resp.Check)
+		}
+		if !hasFinding(resp, "lsp.go:9:2", `"slices".SortFunc`) {
+			t.Errorf("findings %+v do not include slices.SortFunc", resp.Findings)
+		}
+
+		status, resp = postJSON(ScanRequest{Dir: "../testdata/lsp"})
+		if status != http.StatusBadRequest {
+			t.Errorf("got status %d for a directory outside the root, want %d [%s]", status, http.StatusBadRequest, resp.Error)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		// A module zip file, as from a module proxy.
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		err := fs.WalkDir(os.DirFS("testdata/lsp"), ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path.Join("testdata/lsp", name))
+			if err != nil {
+				return err
+			}
+			w, err := zw.Create(path.Join("example.com/lsp@v1.0.0", name))
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		status, resp := post(context.Background(), "application/zip", "?check=1", buf.Bytes())
+		if status != http.StatusOK {
+			t.Fatalf("got status %d [%s]", status, resp.Error)
+		}
+		if resp.Version != 21 {
+			t.Errorf("got version %d, want 21", resp.Version)
+		}
+		if resp.Check == nil || resp.Check.OK {
+			t.Errorf("got check %+v, want not ok", resp.Check)
+		}
+	})
+
+	t.Run("zip limits", func(t *testing.T) {
+		zipped := func(files map[string]string) []byte {
+			buf := new(bytes.Buffer)
+			zw := zip.NewWriter(buf)
+			for name, contents := range files {
+				w, err := zw.Create(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := w.Write([]byte(contents)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			return buf.Bytes()
+		}
+
+		srv.MaxUnzipped, srv.MaxZipFiles = 1<<10, 3
+		defer func() { srv.MaxUnzipped, srv.MaxZipFiles = 0, 0 }()
+
+		// A small upload that extracts to much more.
+		body := zipped(map[string]string{
+			"go.mod": "module example.com/big\n\ngo 1.20\n",
+			"big.go": "package big\n\n// " + strings.Repeat("x", 1<<20) + "\n",
+		})
+		status, resp := post(context.Background(), "application/zip", "", body)
+		if status != http.StatusRequestEntityTooLarge {
+			t.Errorf("got status %d for a large extraction, want %d [%s]", status, http.StatusRequestEntityTooLarge, resp.Error)
+		}
+
+		body = zipped(map[string]string{
+			"go.mod": "module example.com/many\n\ngo 1.20\n",
+			"a.go":   "package many\n",
+			"b.go":   "package many\n",
+			"c.go":   "package many\n",
+		})
+		status, resp = post(context.Background(), "application/zip", "", body)
+		if status != http.StatusRequestEntityTooLarge {
+			t.Errorf("got status %d for too many files, want %d [%s]", status, http.StatusRequestEntityTooLarge, resp.Error)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		body, err := json.Marshal(ScanRequest{Dir: "lsp"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, resp := post(ctx, "application/json", "", body)
+		if status == http.StatusOK {
+			t.Errorf("got status %d for a canceled request", status)
+		}
+		if !strings.Contains(resp.Error, "context canceled") {
+			t.Errorf("got error %q, want context canceled", resp.Error)
+		}
+	})
+}
@@ -3,8 +3,6 @@ package mingo
 import (
 	"fmt"
 	"go/ast"
-	"go/token"
-	"go/types"
 )
 
 // Bool result tells whether the max known Go version has been reached.
@@ -83,17 +81,6 @@ func (p *pkgScanner) incDecStmt(stmt *ast.IncDecStmt) (bool, error) {
 }
 
 func (p *pkgScanner) assignStmt(stmt *ast.AssignStmt) (bool, error) {
-	switch stmt.Tok {
-	case token.SHL_ASSIGN, token.SHR_ASSIGN:
-		if len(stmt.Rhs) == 1 && p.isSigned(stmt.Rhs[0]) {
-			p.result(posResult{
-				version: 13,
-				pos:     p.fset.Position(stmt.Pos()),
-				desc:    "signed shift count",
-			})
-		}
-	}
-
 	for _, expr := range stmt.Lhs {
 		if isMax, err := p.expr(expr); err != nil || isMax {
 			return isMax, err
@@ -207,14 +194,6 @@ func (p *pkgScanner) forStmt(stmt *ast.ForStmt) (bool, error) {
 }
 
 func (p *pkgScanner) rangeStmt(stmt *ast.RangeStmt) (bool, error) {
-	if stmt.Key == nil && stmt.Value == nil {
-		p.result(posResult{
-			version: 4,
-			pos:     p.fset.Position(stmt.Pos()),
-			desc:    `variable-free "for range" statement`,
-		})
-	}
-
 	if isMax, err := p.expr(stmt.Key); err != nil || isMax {
 		return isMax, err
 	}
@@ -224,36 +203,5 @@ func (p *pkgScanner) rangeStmt(stmt *ast.RangeStmt) (bool, error) {
 	if isMax, err := p.expr(stmt.X); err != nil || isMax {
 		return isMax, err
 	}
-
-	tv, ok := p.info.Types[stmt.X]
-	if !ok {
-		return false, fmt.Errorf("no type info for range expression at %s", p.fset.Position(stmt.X.Pos()))
-	}
-	switch typ := tv.Type.Underlying().(type) {
-	case *types.Basic:
-		switch typ.Kind() {
-		case types.Int, types.Int8, types.Int16, types.Int32, types.Int64, types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
-			// TODO: all integer kinds, or just some?
-			res := posResult{
-				version: 22,
-				pos:     p.fset.Position(stmt.Pos()),
-				desc:    "range over integer",
-			}
-			if p.result(res) {
-				return true, nil
-			}
-		}
-
-	case *types.Signature:
-		res := posResult{
-			version: 23,
-			pos:     p.fset.Position(stmt.Pos()),
-			desc:    "range over function",
-		}
-		if p.result(res) {
-			return true, nil
-		}
-	}
-
 	return p.blockStmt(stmt.Body)
 }
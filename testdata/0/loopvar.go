@@ -0,0 +1,10 @@
+// Loop variables captured by closures that complete within each iteration
+// behave the same before and after Go 1.22.
+func v0_loopvar(ys [][]int, each func([]int, func(int) int), run func(func() int)) int {
+	for _, y := range ys {
+		each(y, func(i int) int { return y[i] })
+		run(func() int { return len(y) })
+		_ = func() int { return len(y) }()
+	}
+	return 0
+}
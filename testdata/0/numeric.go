@@ -0,0 +1,4 @@
+// Hexadecimal integers and decimal imaginary literals predate Go 1.13.
+func v0_numeric() (int, complex128) {
+	return 0xff + 0XFF + 0177, 2i + 1.5i + 1e3i + 0123i
+}
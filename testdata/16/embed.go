@@ -0,0 +1,4 @@
+import _ "embed"
+
+//go:embed foo.go
+var v16_Src string
@@ -0,0 +1,5 @@
+import "unsafe"
+
+func v17_unsafe(p *byte) []byte {
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(p), 1)), 2)
+}
@@ -0,0 +1,4 @@
+import "embed"
+
+//go:embed all:foo.go
+var v18_Files embed.FS
@@ -0,0 +1,14 @@
+func v20_Eq[T comparable](a, b T) bool { return a == b }
+
+type v20_Pair[K comparable, V any] struct {
+	k K
+	v V
+}
+
+var v20_EqAny = v20_Eq[any]
+
+var v20_EqErr = v20_Eq(error(nil), error(nil))
+
+type v20_Named struct{ x any }
+
+var v20_P v20_Pair[v20_Named, int]
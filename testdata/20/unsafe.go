@@ -0,0 +1,5 @@
+import "unsafe"
+
+func v20_unsafe(s string, b []byte) (string, *byte, *byte) {
+	return unsafe.String(unsafe.StringData(s), len(s)), unsafe.StringData(s), unsafe.SliceData(b)
+}
@@ -0,0 +1,5 @@
+func v21_Apply[T any](f func(T) T, x T) T { return f(x) }
+
+var v21_F func(int) int = v18_C1
+
+var v21_G = v21_Apply(v18_C1, 3)
@@ -0,0 +1,7 @@
+func loopvar22() []func() int {
+	var result []func() int
+	for i := 0; i < 3; i++ {
+		result = append(result, func() int { return i })
+	}
+	return result
+}
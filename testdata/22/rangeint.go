@@ -0,0 +1,6 @@
+func v22_rangeint(p uintptr) (sum uintptr) {
+	for i := range p {
+		sum += i
+	}
+	return sum
+}
@@ -0,0 +1,11 @@
+package a // want package:`1\.24 \(import "example.com/analyzer/b": .*\)`
+
+import (
+	"slices"
+
+	"example.com/analyzer/b"
+)
+
+func Repeat(s []int) []int {
+	return slices.Repeat(s, b.Max(1, 2)) // want `"slices"\.Repeat requires Go 1\.23, above target 1\.22`
+}
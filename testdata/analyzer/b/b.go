@@ -0,0 +1,13 @@
+package b // want package:`1\.24 \(.*"crypto/sha3"\.New256\)`
+
+import "crypto/sha3"
+
+func Max(x, y int) int {
+	return max(x, y)
+}
+
+func Sum(b []byte) []byte {
+	h := sha3.New256() // want `"crypto/sha3"\.New256 requires Go 1\.24, above target 1\.22`
+	h.Write(b)         // want `"crypto/sha3"\.Write requires Go 1\.24`
+	return h.Sum(nil)  // want `"crypto/sha3"\.Sum requires Go 1\.24`
+}
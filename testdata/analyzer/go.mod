@@ -0,0 +1,3 @@
+module example.com/analyzer
+
+go 1.22
@@ -0,0 +1,16 @@
+package a // want package:`1\.24 \(import "example.com/analyzertarget/b": .*\)`
+
+// The same code as testdata/analyzer,
+// for a run of the analyzer with a target of 1.24:
+// the package facts are the same,
+// but there are no diagnostics.
+
+import (
+	"slices"
+
+	"example.com/analyzertarget/b"
+)
+
+func Repeat(s []int) []int {
+	return slices.Repeat(s, b.Max(1, 2))
+}
@@ -0,0 +1,13 @@
+package b // want package:`1\.24 \(.*"crypto/sha3"\.New256\)`
+
+import "crypto/sha3"
+
+func Max(x, y int) int {
+	return max(x, y)
+}
+
+func Sum(b []byte) []byte {
+	h := sha3.New256()
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -0,0 +1,3 @@
+module example.com/analyzertarget
+
+go 1.22
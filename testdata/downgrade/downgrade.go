@@ -0,0 +1,36 @@
+package downgrade
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"example.com/downgrade/lib/v2"
+)
+
+func Trim(s string) string {
+	s, _ = strings.CutPrefix(s, "x")
+	return s
+}
+
+// The import of lib/v2 must survive the rewrite of Trim.
+func Lib() int {
+	return lib.F()
+}
+
+func Has(s []int, n int) bool {
+	return slices.Contains(s, max(n, 0))
+}
+
+func Sum(n int) (sum int) {
+	for i := range n {
+		sum += i
+	}
+	return sum
+}
+
+func Print(x any) {}
+
+func Join(a, b error) error {
+	return errors.Join(a, b)
+}
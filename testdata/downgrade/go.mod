@@ -0,0 +1,3 @@
+module example.com/downgrade
+
+go 1.22
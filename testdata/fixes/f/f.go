@@ -0,0 +1,65 @@
+package f // want package:`1\.22 \(.*range over integer\)`
+
+import (
+	"sort"
+	"time"
+)
+
+func Any(x any) {} // want `"any" builtin requires Go 1\.18`
+
+func Literals() []int {
+	return []int{
+		0b1010,    // want `expanded numeric literal requires Go 1\.13`
+		0o17,      // want `expanded numeric literal requires Go 1\.13`
+		1_000_000, // want `expanded numeric literal requires Go 1\.13`
+		0x_ff,     // want `expanded numeric literal requires Go 1\.13`
+	}
+}
+
+func Imag() complex128 {
+	return 0b11i // want `expanded numeric literal requires Go 1\.13`
+}
+
+func MinMax(a, b, c int, d time.Duration) {
+	_ = max(a, b, c)        // want `use of max builtin requires Go 1\.21`
+	_ = min(a)              // want `use of min builtin requires Go 1\.21`
+	_ = max(1, 2)           // want `use of max builtin requires Go 1\.21`
+	_ = min(time.Second, d) // want `use of min builtin requires Go 1\.21`
+	_ = min(float64(a), 1)  // want `use of min builtin requires Go 1\.21`
+}
+
+func Range(n int, s []int) (sum int) {
+	for i := range n { // want `range over integer requires Go 1\.22`
+		sum += i
+	}
+	for i := range 10 { // want `range over integer requires Go 1\.22`
+		defer func() { sum += i }() // want `warning: loop variable captured by a closure or pointer behaves differently before Go 1\.22`
+	}
+	for i := range len(s) { // want `range over integer requires Go 1\.22`
+		s = append(s, i)
+	}
+	return sum
+}
+
+var limit = 3
+
+// The loop condition must not see changes to the range expression,
+// which code elsewhere can make here.
+func RangeHoisted(n int) (sum int) {
+	dec := func() { n-- }
+	for i := range n { // want `range over integer requires Go 1\.22`
+		sum += i
+		dec()
+	}
+	for i := range limit { // want `range over integer requires Go 1\.22`
+		n0 := i
+		sum += n0
+	}
+	return sum
+}
+
+func Sort(ys [][]int) {
+	for _, y := range ys {
+		sort.Slice(y, func(i, j int) bool { return y[i] < y[j] }) // want `warning: loop variable possibly captured beyond its iteration behaves differently before Go 1\.22`
+	}
+}
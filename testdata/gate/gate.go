@@ -0,0 +1,38 @@
+package gate
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Has needs Go 1.21 for slices.Contains,
+// but its signature does not,
+// so it can move and leave a stub.
+func Has(words []string, word string) bool {
+	return slices.Contains(words, word)
+}
+
+// Count stays put, calling Has.
+func Count(s, word string) int {
+	before, _, _ := strings.Cut(s, "#")
+	if Has(strings.Fields(before), word) {
+		return 1
+	}
+	return 0
+}
+
+// Lines needs Go 1.23 in its signature, so it cannot have a stub.
+func Lines(s string) iter.Seq[string] {
+	return strings.Lines(s)
+}
+
+// Function seq must move with Lines.
+func seq(s string) any {
+	return Lines(s)
+}
+
+// Total stays put.
+func Total(s string) int {
+	return len(s)
+}
@@ -0,0 +1,15 @@
+// Deprecated: use x.y/z instead.
+module x.y/gomod
+
+go 1.22
+
+toolchain go1.23.4
+
+godebug (
+	default=go1.21
+	panicnil=1
+)
+
+retract v1.0.0
+
+tool x.y/gomod/cmd/gen
@@ -0,0 +1,5 @@
+package gomod
+
+func F() int {
+	return 7
+}
@@ -0,0 +1,10 @@
+package lsp
+
+import (
+	"slices"
+	"strings"
+)
+
+func Sort(words []string) {
+	slices.SortFunc(words, strings.Compare)
+}
@@ -0,0 +1,7 @@
+module example.com/user
+
+go 1.18
+
+require example.com/lib v1.2.0
+
+replace example.com/lib => ./lib
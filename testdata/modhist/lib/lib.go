@@ -0,0 +1,16 @@
+package lib
+
+type T struct {
+	X int
+	Y int
+}
+
+func (T) M() {}
+
+func (T) N() {}
+
+func Foo() int { return 1 }
+
+func Bar() int { return 2 }
+
+func Baz() int { return 3 }
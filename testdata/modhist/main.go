@@ -0,0 +1,13 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/lib"
+)
+
+func main() {
+	var t lib.T
+	t.M()
+	fmt.Println(lib.Bar(), t.X)
+}
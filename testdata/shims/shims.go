@@ -0,0 +1,30 @@
+package shims
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+func Trim(s string) string {
+	s, _ = strings.CutPrefix(s, "x")
+	return s
+}
+
+func Has(s []int, n int) bool {
+	return slices.Contains(s, n)
+}
+
+func Day(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+func Appendf(b []byte, n int) []byte {
+	return fmt.Appendf(b, "%d", n)
+}
+
+func Join(a, b error) error {
+	return errors.Join(a, b)
+}
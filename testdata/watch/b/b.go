@@ -0,0 +1,8 @@
+package b
+
+import "example.com/watch/a"
+
+func Sort(words []string) []string {
+	a.Sort(words)
+	return words
+}
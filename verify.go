@@ -0,0 +1,121 @@
+package mingo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"runtime"
+)
+
+// VerifyError is the error returned by [Scanner.ScanDir] or [Scanner.ScanPackages]
+// when [Scanner.Verify] is enabled
+// and the type checker disagrees with the language version computed for a package.
+type VerifyError struct {
+	PkgPath   string
+	Computed  Result // The language feature requiring the highest version of Go in the package (0 if none).
+	GoVersion string // The version given to the type checker, e.g. "go1.20".
+
+	// Err is the first type error at GoVersion,
+	// which is the computed version.
+	// It is nil when the discrepancy is that the package type-checks at GoVersion,
+	// which is the version below the computed one.
+	Err error
+}
+
+func (e VerifyError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("package %s does not type-check at %s, the computed minimum for its language features [%s]: %s", e.PkgPath, e.GoVersion, e.Computed, e.Err)
+	}
+	return fmt.Sprintf("package %s type-checks at %s, below the computed minimum for its language features [%s]", e.PkgPath, e.GoVersion, e.Computed)
+}
+
+func (e VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// The type checker enforces the language changes of this version of Go and later.
+// Features from earlier versions compile with any go directive,
+// so there is nothing to cross-check below it.
+const verifyMin = 9
+
+// Method verify type-checks the package's files
+// at the language version computed for it
+// and at the version below that,
+// returning a [VerifyError] unless the first succeeds and the second fails.
+//
+// Only the package's language features count,
+// since the type checker does not know when stdlib identifiers were added.
+// Nor do the [Rule.Semantic] ones,
+// which compile with any version,
+// or compiler directives,
+// which are not the type checker's business.
+func (p *pkgScanner) verify(files []*ast.File) error {
+	if p.pkg == nil {
+		return nil
+	}
+
+	v := p.lang.Version()
+	goVersion := fmt.Sprintf("go1.%d", max(v, 1))
+
+	var first error
+	p.recheck(files, goVersion, func(err types.Error) {
+		if first == nil {
+			first = err
+		}
+	})
+	if first != nil {
+		return VerifyError{PkgPath: p.pkg.Path(), Computed: p.lang, GoVersion: goVersion, Err: first}
+	}
+
+	if v < verifyMin {
+		return nil
+	}
+
+	goVersion = fmt.Sprintf("go1.%d", v-1)
+	var failed bool
+	p.recheck(files, goVersion, func(types.Error) { failed = true })
+	if !failed {
+		return VerifyError{PkgPath: p.pkg.Path(), Computed: p.lang, GoVersion: goVersion}
+	}
+	return nil
+}
+
+// Method recheck type-checks the package's files again,
+// as the given version of Go would,
+// calling errf for each error.
+// The package's imports are the ones it was loaded with.
+func (p *pkgScanner) recheck(files []*ast.File, goVersion string, errf func(types.Error)) {
+	imports := make(map[string]*types.Package)
+	for _, imp := range p.pkg.Imports() {
+		imports[imp.Path()] = imp
+	}
+
+	conf := &types.Config{
+		GoVersion: goVersion,
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == "unsafe" {
+				return types.Unsafe, nil
+			}
+			if imp, ok := imports[path]; ok {
+				return imp, nil
+			}
+			return nil, fmt.Errorf("package %s not loaded", path)
+		}),
+		FakeImportC: true,
+		Sizes:       types.SizesFor("gc", runtime.GOARCH),
+		Error: func(err error) {
+			if terr, ok := err.(types.Error); ok {
+				errf(terr)
+			}
+		},
+	}
+
+	// The errors are the point; the package is discarded.
+	_, _ = conf.Check(p.pkg.Path(), p.fset, files, nil)
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
@@ -0,0 +1,80 @@
+package mingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bobg/errors"
+)
+
+func TestVerify(t *testing.T) {
+	write := func(t *testing.T, src string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module foo\n\ngo 1.21\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "foo.go"), []byte("package foo\n\n"+src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	s := Scanner{Verify: true}
+
+	t.Run("agree", func(t *testing.T) {
+		dir := write(t, "func F[T any](x T) T { return x }\n\nvar X = min(1, 2)\n")
+		res, err := s.ScanDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Version() != 21 {
+			t.Errorf("got version %d, want 21", res.Version())
+		}
+	})
+
+	t.Run("hex", func(t *testing.T) {
+		// Hexadecimal integers and decimal imaginary literals predate Go 1.13,
+		// so the highest language feature here needs no particular version.
+		dir := write(t, "var X = 0xff\n\nvar Y = 2i\n")
+		res, err := s.ScanDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Version() != 0 {
+			t.Errorf("got version %d, want 0 [%s]", res.Version(), res)
+		}
+	})
+
+	t.Run("lower", func(t *testing.T) {
+		// The type checker allows a constant signed shift count before Go 1.13.
+		dir := write(t, "var X = 52 >> int(2)\n")
+		_, err := s.ScanDir(dir)
+		verr, ok := errors.AsType[VerifyError](err)
+		if !ok {
+			t.Fatalf("got error %v, want VerifyError", err)
+		}
+		if verr.GoVersion != "go1.12" || verr.Err != nil {
+			t.Errorf("got %s, want a clean type-check at go1.12", verr)
+		}
+	})
+
+	t.Run("higher", func(t *testing.T) {
+		// Without the rules for min and max,
+		// mingo computes no language version for this code.
+		for _, id := range []string{"min", "max"} {
+			r := unregister(id)
+			defer Register(r)
+		}
+
+		dir := write(t, "var X = min(1, 2)\n")
+		_, err := s.ScanDir(dir)
+		verr, ok := errors.AsType[VerifyError](err)
+		if !ok {
+			t.Fatalf("got error %v, want VerifyError", err)
+		}
+		if verr.GoVersion != "go1.1" || verr.Err == nil {
+			t.Errorf("got %s, want a type error at go1.1", verr)
+		}
+	})
+}
@@ -0,0 +1,421 @@
+package mingo
+
+import (
+	"context"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bobg/errors"
+	"golang.org/x/tools/go/packages"
+)
+
+// WatchUpdate is what [Scanner.Watch] reports after each scan.
+type WatchUpdate struct {
+	Result   Result   // The module's minimum version of Go.
+	Packages []string // The packages that were (re)scanned.
+
+	// Added and Removed are the findings
+	// (uses of language features and stdlib identifiers that need some version of Go)
+	// that appeared or disappeared in this scan.
+	// The first update lists every finding as added.
+	Added, Removed []Result
+
+	// Err is a problem loading or scanning some packages,
+	// probably because of a file in mid-edit.
+	// Their earlier findings are kept until they load again.
+	Err error
+}
+
+// Watch scans the module in dir,
+// then polls its files every interval
+// and rescans when they change,
+// until ctx is canceled.
+// It calls report after each scan,
+// stopping if report returns an error.
+//
+// Type information is kept between scans.
+// A change to a package's files reloads only that package
+// and the packages in the module that depend on it, directly or indirectly.
+// A change to go.mod reloads everything
+// (and, with s.Deps, rescans the dependencies).
+//
+// The settings in s apply,
+// except for Check and Strict.
+func (s *Scanner) Watch(ctx context.Context, dir string, interval time.Duration, report func(WatchUpdate) error) error {
+	if err := s.ensureHistory(); err != nil {
+		return err
+	}
+
+	w := &watchState{s: s, dir: dir}
+	update, err := w.loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	if err := report(update); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		snapshot, err := w.snapshot()
+		if err != nil {
+			return err
+		}
+		changed, gomodChanged := w.changedDirs(snapshot)
+
+		switch {
+		case gomodChanged:
+			update, err = w.loadAll(ctx)
+		case len(changed) > 0:
+			update, err = w.reload(ctx, changed, snapshot)
+		default:
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		if err := report(update); err != nil {
+			return err
+		}
+	}
+}
+
+type watchState struct {
+	s   *Scanner
+	dir string
+
+	root  string                       // the module root directory
+	gomod string                       // the go.mod file
+	deps  Result                       // the result of scanning dependencies
+	pkgs  map[string]*packages.Package // by ID
+	scans map[string]*pkgScanner       // by ID
+	files map[string]fileStamp         // the files at the last scan
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (w *watchState) load(ctx context.Context, patterns ...string) ([]*packages.Package, error) {
+	conf := &packages.Config{
+		Context: ctx,
+		Mode:    Mode | packages.NeedImports,
+		Dir:     w.dir,
+		Tests:   w.s.Tests,
+	}
+	pkgs, err := packages.Load(conf, patterns...)
+	return pkgs, errors.Wrap(err, "loading packages")
+}
+
+// Method loadAll loads and scans the whole module.
+func (w *watchState) loadAll(ctx context.Context) (WatchUpdate, error) {
+	pkgs, err := w.load(ctx, "./...")
+	if err != nil {
+		return WatchUpdate{}, err
+	}
+	if len(pkgs) == 0 || pkgs[0].Module == nil {
+		return WatchUpdate{}, errors.New("no module packages")
+	}
+
+	w.root = pkgs[0].Module.Dir
+	w.gomod = pkgs[0].Module.GoMod
+
+	w.deps = intResult(0)
+	if w.s.Deps {
+		st := w.s.newScanState()
+		if err := st.scanDeps(ctx, w.gomod); err != nil {
+			return WatchUpdate{}, errors.Wrap(err, "scanning dependencies")
+		}
+		w.deps = st.res
+	}
+
+	old := w.findings()
+	w.pkgs = make(map[string]*packages.Package)
+	w.scans = make(map[string]*pkgScanner)
+
+	update := w.scan(pkgs, old)
+
+	if w.files, err = w.snapshot(); err != nil {
+		return WatchUpdate{}, err
+	}
+	return update, nil
+}
+
+// Method reload reloads and rescans the packages in the given directories
+// and the packages that depend on them.
+func (w *watchState) reload(ctx context.Context, dirs map[string]bool, snapshot map[string]fileStamp) (WatchUpdate, error) {
+	w.files = snapshot
+
+	// Find the import paths of the affected packages.
+	affected := make(map[string]bool)
+	for _, pkg := range w.pkgs {
+		if dirs[pkgDir(pkg)] {
+			affected[pkg.PkgPath] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, pkg := range w.pkgs {
+			if affected[pkg.PkgPath] {
+				continue
+			}
+			for imp := range pkg.Imports {
+				if affected[imp] {
+					affected[pkg.PkgPath] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, pkg := range w.pkgs {
+		if affected[pkg.PkgPath] {
+			if dir := pkgDir(pkg); dir != "" {
+				dirs[dir] = true
+			}
+		}
+	}
+
+	var patterns []string
+	for dir := range dirs {
+		rel, err := filepath.Rel(w.root, dir)
+		if err != nil {
+			return WatchUpdate{}, err
+		}
+		patterns = append(patterns, "./"+filepath.ToSlash(rel))
+	}
+	slices.Sort(patterns)
+
+	pkgs, err := w.load(ctx, patterns...)
+	if err != nil {
+		return WatchUpdate{}, err
+	}
+
+	old := w.findings()
+
+	// Forget the packages that are gone
+	// (e.g. because their directory no longer has Go files).
+	// The others are replaced in w.scan.
+	loaded := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) > 0 {
+			loaded[pkg.ID] = true
+		}
+	}
+	for id, pkg := range w.pkgs {
+		if dirs[pkgDir(pkg)] && !loaded[id] {
+			delete(w.pkgs, id)
+			delete(w.scans, id)
+		}
+	}
+
+	return w.scan(pkgs, old), nil
+}
+
+// Method scan scans pkgs,
+// adding them to w,
+// and compares the findings with old.
+func (w *watchState) scan(pkgs []*packages.Package, old []posResult) WatchUpdate {
+	var update WatchUpdate
+
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 && len(pkg.Errors) > 0 {
+			// E.g. a directory whose last Go file was removed.
+			continue
+		}
+		update.Packages = append(update.Packages, pkg.ID)
+
+		if len(pkg.Errors) > 0 {
+			for _, e := range pkg.Errors {
+				update.Err = errors.Join(update.Err, LoadError{Err: e, Path: pkg.PkgPath})
+			}
+			if _, ok := w.pkgs[pkg.ID]; !ok {
+				w.pkgs[pkg.ID] = pkg
+			}
+			continue
+		}
+
+		p := w.s.newPkgScanner(pkg.Types, pkg.Fset, pkg.TypesInfo)
+		p.report = true
+		if err := p.files(pkg.Syntax); err != nil {
+			update.Err = errors.Join(update.Err, errors.Wrapf(err, "scanning package %s", pkg.PkgPath))
+			continue
+		}
+		w.pkgs[pkg.ID] = pkg
+		w.scans[pkg.ID] = p
+	}
+	slices.Sort(update.Packages)
+
+	update.Result = w.deps
+	for _, p := range w.scans {
+		if p.res.Version() > update.Result.Version() {
+			update.Result = p.res
+		}
+	}
+
+	update.Added, update.Removed = diffFindings(old, w.findings())
+	return update
+}
+
+// Method findings returns the findings in all the scanned packages,
+// without the duplicates from test variants.
+func (w *watchState) findings() []posResult {
+	var (
+		result []posResult
+		seen   = make(map[findingKey]bool)
+	)
+	for _, id := range slices.Sorted(maps.Keys(w.scans)) {
+		for _, f := range w.scans[id].findings {
+			key := findingKey{f.pos.Filename, f.pos.Offset, f.desc}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+type findingKey struct {
+	filename string
+	offset   int
+	desc     string
+}
+
+// Function diffFindings compares two sets of findings.
+// Findings are matched by file, description, and version,
+// not position,
+// so that edits elsewhere in a file do not make every later finding look new.
+func diffFindings(old, new []posResult) (added, removed []Result) {
+	type key struct {
+		filename, desc string
+		version        int
+	}
+	count := func(ff []posResult) map[key][]posResult {
+		m := make(map[key][]posResult)
+		for _, f := range ff {
+			k := key{f.pos.Filename, f.desc, f.version}
+			m[k] = append(m[k], f)
+		}
+		return m
+	}
+	oldCount, newCount := count(old), count(new)
+
+	for k, ff := range newCount {
+		for _, f := range ff[min(len(ff), len(oldCount[k])):] {
+			added = append(added, f)
+		}
+	}
+	for k, ff := range oldCount {
+		for _, f := range ff[min(len(ff), len(newCount[k])):] {
+			removed = append(removed, f)
+		}
+	}
+
+	byPos := func(a, b Result) int {
+		return comparePositions(a.(posResult).pos, b.(posResult).pos)
+	}
+	slices.SortFunc(added, byPos)
+	slices.SortFunc(removed, byPos)
+	return added, removed
+}
+
+// Method snapshot records the Go files in the module and its go.mod file.
+// It skips the directories that the go command ignores,
+// and nested modules.
+func (w *watchState) snapshot() (map[string]fileStamp, error) {
+	result := make(map[string]fileStamp)
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed during the walk
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path == w.root {
+				return nil
+			}
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") && path != w.gomod {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return result, errors.Wrapf(err, "walking %s", w.root)
+}
+
+// Method changedDirs compares a snapshot with the last one,
+// returning the directories of the Go files that were added, removed, or changed,
+// and whether go.mod changed.
+func (w *watchState) changedDirs(snapshot map[string]fileStamp) (map[string]bool, bool) {
+	var (
+		dirs         = make(map[string]bool)
+		gomodChanged bool
+	)
+	note := func(path string) {
+		if path == w.gomod {
+			gomodChanged = true
+		} else {
+			dirs[filepath.Dir(path)] = true
+		}
+	}
+	for path, stamp := range snapshot {
+		if prev, ok := w.files[path]; !ok || !prev.modTime.Equal(stamp.modTime) || prev.size != stamp.size {
+			note(path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := snapshot[path]; !ok {
+			note(path)
+		}
+	}
+	return dirs, gomodChanged
+}
+
+// Function pkgDir returns the directory of a package's files,
+// or "" if it has none outside GOCACHE
+// (like the generated main package of a test).
+func pkgDir(pkg *packages.Package) string {
+	for _, filename := range pkg.GoFiles {
+		if isCache, err := isCacheFile(filename); err == nil && !isCache {
+			return filepath.Dir(filename)
+		}
+	}
+	return ""
+}
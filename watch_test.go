@@ -0,0 +1,107 @@
+package mingo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/watch")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var (
+		updates = make(chan WatchUpdate)
+		errch   = make(chan error, 1)
+	)
+	go func() {
+		s := new(Scanner)
+		errch <- s.Watch(ctx, dir, 10*time.Millisecond, func(u WatchUpdate) error {
+			select {
+			case updates <- u:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	next := func() WatchUpdate {
+		select {
+		case u := <-updates:
+			return u
+		case err := <-errch:
+			t.Fatalf("Watch returned early: %v", err)
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+		return WatchUpdate{}
+	}
+	descs := func(rr []Result) []string {
+		var result []string
+		for _, r := range rr {
+			result = append(result, r.(posResult).desc)
+		}
+		return result
+	}
+
+	u := next()
+	if u.Err != nil {
+		t.Fatal(u.Err)
+	}
+	if u.Result.Version() != 21 {
+		t.Errorf("got initial version %d, want 21", u.Result.Version())
+	}
+	if want := []string{"example.com/watch/a", "example.com/watch/b"}; !slices.Equal(u.Packages, want) {
+		t.Errorf("got initial packages %v, want %v", u.Packages, want)
+	}
+	if !slices.Contains(descs(u.Added), `"slices".SortFunc`) {
+		t.Errorf("initial findings %v do not include slices.SortFunc", descs(u.Added))
+	}
+
+	// Make sure the change is visible in the file's modification time.
+	time.Sleep(10 * time.Millisecond)
+
+	const newA = `package a
+
+import "sort"
+
+func Sort(words []string) {
+	sort.Strings(words)
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "a", "a.go"), []byte(newA), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	u = next()
+	if u.Err != nil {
+		t.Fatal(u.Err)
+	}
+	if u.Result.Version() >= 21 {
+		t.Errorf("got version %d after change, want less than 21", u.Result.Version())
+	}
+	// Package b depends on a, so it is reloaded too.
+	if want := []string{"example.com/watch/a", "example.com/watch/b"}; !slices.Equal(u.Packages, want) {
+		t.Errorf("got reloaded packages %v, want %v", u.Packages, want)
+	}
+	if got, want := descs(u.Removed), []string{`"slices".SortFunc`, "generic instantiation", `"strings".Compare`}; !slices.Equal(got, want) {
+		t.Errorf("got removed %v, want %v", got, want)
+	}
+	if got := descs(u.Added); len(got) != 0 {
+		t.Errorf("got added %v, want none", got)
+	}
+
+	cancel()
+	if err := <-errch; err != context.Canceled {
+		t.Errorf("got %v from Watch, want %v", err, context.Canceled)
+	}
+}